@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+	"github.com/nilptrderef/bgate/store"
+	"github.com/spf13/cobra"
+)
+
+var bookmarks = &cobra.Command{
+	Use:   "bookmarks",
+	Short: "List, add and remove bookmarks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := store.Open()
+		cobra.CheckErr(err)
+		defer s.Close()
+
+		marks, err := s.Bookmarks()
+		cobra.CheckErr(err)
+
+		for _, mark := range marks {
+			line := fmt.Sprintf("%3d  %s", mark.ID, model.BookStyle.Render(mark.Reference().String()))
+			if mark.Label != "" {
+				line += "  " + mark.Label
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+var bookmarksAdd = &cobra.Command{
+	Use:   "add [flags] <reference>",
+	Short: "Bookmark a verse or chapter",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		label, _ := cmd.Flags().GetString("label")
+
+		ranges, err := search.ParseReference(strings.Join(args, " "))
+		cobra.CheckErr(err)
+		// Only where a bookmark starts is stored, so anything longer than a
+		// single verse or chapter would be cut short without saying so
+		if len(ranges) != 1 || ranges[0].Start != ranges[0].End {
+			cobra.CheckErr(fmt.Errorf("A bookmark can only point to a single verse or chapter, not %s", strings.Join(args, " ")))
+		}
+
+		s, err := store.Open()
+		cobra.CheckErr(err)
+		defer s.Close()
+
+		mark, err := s.AddBookmark(ranges[0].Start, label)
+		cobra.CheckErr(err)
+		fmt.Printf("Added bookmark %d: %s\n", mark.ID, mark)
+	},
+}
+
+var bookmarksRemove = &cobra.Command{
+	Use:     "rm <id>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove bookmarks by their id",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := store.Open()
+		cobra.CheckErr(err)
+		defer s.Close()
+
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				cobra.CheckErr(fmt.Errorf("Invalid bookmark id %q", arg))
+			}
+			cobra.CheckErr(s.RemoveBookmark(id))
+		}
+	},
+}
+
+func init() {
+	bookmarksAdd.Flags().StringP("label", "l", "", "A label to remember the bookmark by.")
+	bookmarks.AddCommand(bookmarksAdd)
+	bookmarks.AddCommand(bookmarksRemove)
+	root.AddCommand(bookmarks)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/nilptrderef/bgate/search"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cache = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of passages read from BibleGateway",
+	Long: `Manage the cache of passages read from BibleGateway
+
+Passages read from BibleGateway are kept for --cache-ttl, so they can be read
+again without reaching out to it, or when offline. Once the cache is bigger than
+--cache-size megabytes the passages read longest ago are dropped.`,
+}
+
+var cacheStats = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how much is cached",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := openCache()
+		cobra.CheckErr(err)
+		defer c.Close()
+
+		stats, err := c.Stats()
+		cobra.CheckErr(err)
+
+		fmt.Printf("Path:    %s\n", stats.Path)
+		fmt.Printf("Entries: %d\n", stats.Entries)
+		fmt.Printf("Size:    %s of %s\n", humanSize(stats.Size), humanSize(cacheSize()))
+		if stats.Entries == 0 {
+			return
+		}
+		fmt.Printf("Oldest:  %s\n", stats.Oldest.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("Newest:  %s\n", stats.Newest.Local().Format("2006-01-02 15:04"))
+
+		var translations []string
+		for translation := range stats.Translations {
+			translations = append(translations, translation)
+		}
+		slices.Sort(translations)
+		for _, translation := range translations {
+			fmt.Printf("  %-10s %d\n", translation, stats.Translations[translation])
+		}
+	},
+}
+
+var cacheClear = &cobra.Command{
+	Use:   "clear [translation]...",
+	Short: "Empty the cache, or only remove the given translations from it",
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := openCache()
+		cobra.CheckErr(err)
+		defer c.Close()
+
+		cobra.CheckErr(c.Clear(args...))
+	},
+}
+
+// cacheSize returns the configured size of the cache in bytes.
+func cacheSize() int64 {
+	return viper.GetInt64("cache-size") << 20
+}
+
+func openCache() (*search.Cache, error) {
+	return search.OpenCache(viper.GetDuration("cache-ttl"), cacheSize())
+}
+
+func init() {
+	cacheClear.ValidArgsFunction = completeTranslations
+	cache.AddCommand(cacheStats)
+	cache.AddCommand(cacheClear)
+	root.AddCommand(cache)
+}
@@ -1,12 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path"
 	"time"
 
-	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nilptrderef/bgate/search"
 	"github.com/spf13/cobra"
@@ -16,68 +14,56 @@ import (
 var download = &cobra.Command{
 	Use:   "download",
 	Short: "Download a translation of the Bible for local usage rather than reaching out to BibleGateway",
+	Long: `Download a translation of the Bible for local usage rather than reaching out to BibleGateway
+
+Any existing copy of the translation is only replaced once the download has
+completed. If it is interrupted, run it again with --resume to pick up where it
+stopped.`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("translation", cmd.Flag("translation"))
 		viper.BindPFlag("delay", cmd.Flag("delay"))
+		viper.BindPFlag("workers", cmd.Flag("workers"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		translation := viper.GetString("translation")
 		delay := viper.GetInt("delay")
+		workers := viper.GetInt("workers")
+		resume, _ := cmd.Flags().GetBool("resume")
 
-		remote := search.NewRemote(translation)
-
-		books, err := remote.Booklist()
-		cobra.CheckErr(err)
-		if len(books) == 0 {
-			cobra.CheckErr(fmt.Errorf("No books found for translation: %s", translation))
+		// Without an index the download would be of no use to grep, which
+		// is better found out before it starts than after it finishes
+		if !search.FulltextSupported() {
+			cobra.CheckErr(errors.New("This build of bgate can't index translations for searching by their words, install it with `make install` or `go install -tags sqlite_fts5` to download them"))
 		}
 
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
-
-		bgatepath := path.Join(home, ".bgate")
-		err = os.MkdirAll(bgatepath, 0755)
-		cobra.CheckErr(err)
-
-		sqlpath := path.Join(bgatepath, fmt.Sprintf("%s.sql", translation))
-		os.Remove(sqlpath)
-
-		db, err := sqlx.Open("sqlite3", sqlpath)
-		cobra.CheckErr(err)
-		defer db.Close()
-
-		_, err = db.Exec(`
-			CREATE TABLE IF NOT EXISTS verses (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			book TEXT,
-			chapter INTEGER,
-			number INTEGER,
-			part INTEGER,
-			text TEXT,
-			title TEXT
-		)`)
+		remote := newRemote(translation)
+		validateTranslation(cmd.Context(), remote)
+		d, err := search.NewDownload(cmd.Context(), remote, resume)
 		cobra.CheckErr(err)
+		defer d.Close()
 
-		for _, book := range books {
-			fmt.Printf("Downloading %s...\n", book.Name)
-
-			for chapter := range book.Chapters {
-				verses, err := remote.Query(fmt.Sprintf("%s %d", book.Name, chapter+1))
-				cobra.CheckErr(err)
+		limiter := search.NewLimiter(time.Duration(delay)*time.Millisecond, 1)
 
-				for _, verse := range verses {
-					_, err = db.Exec("insert into verses (book, chapter, number, part, text, title) values (?, ?, ?, ?, ?, ?)", book.Name, verse.Chapter, verse.Number, verse.Part, verse.Text, verse.Title)
-					cobra.CheckErr(err)
-				}
-
-				time.Sleep(time.Duration(delay) * time.Millisecond)
-			}
+		if interactive() {
+			err = runProgressBar(cmd.Context(), d, workers, limiter)
+		} else {
+			err = runLogged(cmd.Context(), d, workers, limiter)
+		}
+		if err != nil {
+			d.Close()
+			cobra.CheckErr(fmt.Errorf("Download interrupted, run again with --resume to continue: %w", err))
 		}
+
+		fmt.Println("Building search index...")
+		cobra.CheckErr(d.Finish())
 	},
 }
 
 func init() {
 	download.Flags().StringP("translation", "t", "ESV", "The translation of the Bible to search for.")
-	download.Flags().IntP("delay", "d", 100, "Number of milliseconds to wait between requests.")
+	download.RegisterFlagCompletionFunc("translation", completeTranslations)
+	download.Flags().IntP("delay", "d", 100, "Number of milliseconds to wait between requests, shared by all workers.")
+	download.Flags().IntP("workers", "w", 1, "Number of chapters to download at the same time.")
+	download.Flags().Bool("resume", false, "Continue an interrupted download rather than starting over.")
 	root.AddCommand(download)
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nilptrderef/bgate/search"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var grep = &cobra.Command{
+	Use:   "grep [flags] <words>",
+	Short: "Search a downloaded translation for verses containing words",
+	Long: `Search a downloaded translation for verses containing words, best match first.
+
+Every word must appear in a verse for it to match. Wrap words in quotes to
+search for an exact phrase, end a word with * to match anything starting with
+it, and use OR and NOT between words to loosen or exclude matches.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("translation", cmd.Flag("translation"))
+		viper.BindPFlag("padding", cmd.Flag("padding"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		in, _ := cmd.Flags().GetString("in")
+		limit, _ := cmd.Flags().GetInt("limit")
+		translation := viper.GetString("translation")
+		padding := viper.GetInt("padding")
+
+		local, err := search.TranslationHasLocal(translation)
+		cobra.CheckErr(err)
+		if !local {
+			cobra.CheckErr(errors.New("No local copy of translation found. Please use download command for requested translation."))
+		}
+
+		var books search.BookRange
+		if in != "" {
+			books, err = search.ParseBookRange(in)
+			cobra.CheckErr(err)
+		}
+
+		searcher, err := search.NewLocal(translation)
+		cobra.CheckErr(err)
+		defer searcher.Close()
+
+		verses, err := searcher.Grep(cmd.Context(), strings.Join(args, " "), books, limit)
+		cobra.CheckErr(err)
+
+		for _, verse := range verses {
+			fmt.Printf("%s%s %s\n", strings.Repeat(" ", padding), verse.ReferenceString(), strings.TrimSpace(verse.Text))
+		}
+	},
+}
+
+func init() {
+	grep.Flags().StringP("translation", "t", "ESV", "The translation of the Bible to search for.")
+	grep.RegisterFlagCompletionFunc("translation", completeTranslations)
+	grep.Flags().IntP("padding", "p", 0, "Horizontal padding in character count.")
+	grep.Flags().StringP("in", "i", "", "Only search a book or range of books, such as \"Romans-Jude\".")
+	grep.Flags().IntP("limit", "l", 100, "Maximum number of verses to show.")
+	root.AddCommand(grep)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/nilptrderef/bgate/search"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -21,18 +20,8 @@ var list = &cobra.Command{
 		translation := viper.GetString("translation")
 		padding := viper.GetInt("padding")
 
-		local, err := search.TranslationHasLocal(translation)
-		cobra.CheckErr(err)
-
-		var searcher search.Searcher
-		if local {
-			searcher, err = search.NewLocal(translation)
-			cobra.CheckErr(err)
-		} else {
-			searcher = search.NewRemote(translation)
-		}
-
-		books, err := searcher.Booklist()
+		searcher := newSearcher(translation)
+		books, err := searcher.Booklist(cmd.Context())
 		cobra.CheckErr(err)
 
 		for _, book := range books {
@@ -46,6 +35,7 @@ var list = &cobra.Command{
 func init() {
 	list.Flags().StringP("filter", "f", "", "Filter the list of books by name. (Case insensitive)")
 	list.Flags().StringP("translation", "t", "ESV", "The translation of the Bible to search for.")
+	list.RegisterFlagCompletionFunc("translation", completeTranslations)
 	list.Flags().IntP("padding", "p", 0, "Horizontal padding in character count.")
 	root.AddCommand(list)
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nilptrderef/bgate/reader"
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+	"golang.org/x/term"
+)
+
+// printwidth is used when stdout isn't a terminal that can report its size.
+const printwidth = 80
+
+// interactive reports whether stdout is a terminal the reader can take over.
+func interactive() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// printPassage writes a passage to stdout in the given format. Text is laid out
+// the same way as in the reader, for use in pipes and scripts.
+func printPassage(ctx context.Context, searchers []search.Searcher, query string, format reader.Format, padding int, wrap bool, redletter bool) error {
+	if len(searchers) > 1 && format != reader.FormatText {
+		return fmt.Errorf("Only text can be printed for more than one translation at a time")
+	}
+
+	passages := make([][]model.Verse, len(searchers))
+	for i, searcher := range searchers {
+		verses, err := searcher.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		if len(verses) == 0 {
+			return fmt.Errorf("No results found for %q in %s", query, searcher.Translation())
+		}
+		passages[i] = verses
+	}
+
+	if format != reader.FormatText {
+		return reader.WriteVerses(os.Stdout, passages[0], format, 0, wrap, redletter)
+	}
+
+	width := printwidth
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		width = w
+	}
+
+	var content string
+	if len(passages) > 1 {
+		content = reader.RenderParallel(passages, width-(2*padding), redletter)
+	} else {
+		content = reader.RenderVerses(passages[0], width-(2*padding), wrap, redletter)
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		fmt.Println(strings.TrimRight(strings.Repeat(" ", padding)+line, " "))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nilptrderef/bgate/reader/style"
+	"github.com/nilptrderef/bgate/search"
+)
+
+// logInterval is how often progress is logged when output isn't a terminal.
+const logInterval = 10 * time.Second
+
+// downloadStats works out throughput and time remaining for a download. Only
+// the chapters downloaded in this run are counted, so resuming a download
+// doesn't inflate the rate.
+type downloadStats struct {
+	start     time.Time
+	startdone int
+	current   search.Progress
+}
+
+func newDownloadStats(d *search.Download) *downloadStats {
+	done := d.Total() - d.Remaining()
+	return &downloadStats{
+		start:     time.Now(),
+		startdone: done,
+		current:   search.Progress{Done: done, Total: d.Total()},
+	}
+}
+
+// rate returns the number of chapters downloaded per second.
+func (s *downloadStats) rate() float64 {
+	elapsed := time.Since(s.start).Seconds()
+	if elapsed == 0 {
+		return 0
+	}
+	return float64(s.current.Done-s.startdone) / elapsed
+}
+
+func (s *downloadStats) eta() string {
+	rate := s.rate()
+	if rate == 0 {
+		return "unknown"
+	}
+	remaining := float64(s.current.Total-s.current.Done) / rate
+	return (time.Duration(remaining) * time.Second).Round(time.Second).String()
+}
+
+func (s *downloadStats) percent() float64 {
+	if s.current.Total == 0 {
+		return 0
+	}
+	return float64(s.current.Done) / float64(s.current.Total)
+}
+
+func (s *downloadStats) String() string {
+	book := ""
+	if s.current.Book != "" {
+		book = fmt.Sprintf("%s %d, ", s.current.Book, s.current.Chapter)
+	}
+	return fmt.Sprintf("%d/%d chapters (%s%.1f chapters/s, %s left)", s.current.Done, s.current.Total, book, s.rate(), s.eta())
+}
+
+// runLogged downloads while logging progress every so often, for when output
+// isn't a terminal, such as in CI or cron jobs.
+func runLogged(ctx context.Context, d *search.Download, workers int, limiter *search.Limiter) error {
+	stats := newDownloadStats(d)
+	last := time.Now()
+	fmt.Printf("Downloading %s\n", stats)
+
+	err := d.Run(ctx, workers, limiter, func(p search.Progress) {
+		stats.current = p
+		if time.Since(last) >= logInterval || p.Done == p.Total {
+			last = time.Now()
+			fmt.Printf("Downloading %s\n", stats)
+		}
+	})
+	return err
+}
+
+type progressMsg search.Progress
+
+type downloadDoneMsg struct {
+	err error
+}
+
+// progressModel shows a download's progress bar, which can be interrupted with
+// ctrl+c and resumed later.
+type progressModel struct {
+	bar         progress.Model
+	stats       *downloadStats
+	interrupted bool
+	err         error
+}
+
+func (m *progressModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" || msg.String() == "q" {
+			m.interrupted = true
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.bar.Width = max(10, msg.Width-4)
+	case progressMsg:
+		m.stats.current = search.Progress(msg)
+	case downloadDoneMsg:
+		m.err = msg.err
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *progressModel) View() string {
+	var writer strings.Builder
+	writer.WriteString(m.bar.ViewAs(m.stats.percent()) + "\n")
+	writer.WriteString(style.SearchStyle.Render(m.stats.String()) + "\n")
+	return writer.String()
+}
+
+// runProgressBar downloads while showing a progress bar. When interrupted,
+// the download is cancelled and waited for, so it can be safely closed.
+func runProgressBar(ctx context.Context, d *search.Download, workers int, limiter *search.Limiter) error {
+	m := &progressModel{
+		bar:   progress.New(progress.WithDefaultGradient()),
+		stats: newDownloadStats(d),
+	}
+	p := tea.NewProgram(m, tea.WithContext(ctx))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := d.Run(ctx, workers, limiter, func(pr search.Progress) {
+			p.Send(progressMsg(pr))
+		})
+		p.Send(downloadDoneMsg{err})
+	}()
+
+	_, err := p.Run()
+	cancel()
+	<-done
+
+	if m.interrupted {
+		return fmt.Errorf("Interrupted at %s", m.stats)
+	}
+	if err != nil {
+		return err
+	}
+	return m.err
+}
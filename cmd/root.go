@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/nilptrderef/bgate/reader"
 	"github.com/nilptrderef/bgate/search"
+	"github.com/nilptrderef/bgate/store"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
@@ -15,40 +18,84 @@ import (
 )
 
 var root = &cobra.Command{
-	Use:   "bgate [flags] <query>",
+	Use:   "bgate [flags] [query]",
 	Short: "A terminal interface to Bible Gateway",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `A terminal interface to Bible Gateway
+
+Without a query, or with --resume, the last passage read in the translation is
+opened again where it was left.`,
+	Args: cobra.ArbitraryArgs,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("translation", cmd.Flag("translation"))
 		viper.BindPFlag("padding", cmd.Flag("padding"))
 		viper.BindPFlag("wrap", cmd.Flag("wrap"))
 		viper.BindPFlag("force-local", cmd.Flag("force-local"))
 		viper.BindPFlag("force-remote", cmd.Flag("force-remote"))
+		viper.BindPFlag("print", cmd.Flag("print"))
+		viper.BindPFlag("resume", cmd.Flag("resume"))
+		viper.BindPFlag("red-letter", cmd.Flag("red-letter"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		translation := viper.GetString("translation")
+		translations := strings.Split(viper.GetString("translation"), ",")
 		query := strings.Join(args, " ")
 		padding := viper.GetInt("padding")
 		wrap := viper.GetBool("wrap")
+		redletter := viper.GetBool("red-letter")
 
-		local, err := search.TranslationHasLocal(translation)
-		cobra.CheckErr(err)
+		var names []string
+		var searchers []search.Searcher
+		for _, translation := range translations {
+			translation = strings.TrimSpace(translation)
+			if translation == "" {
+				continue
+			}
+			names = append(names, translation)
 
-		if !local && viper.GetBool("force-local") {
-			cobra.CheckErr(errors.New("No local copy of translation found. Please use download command for requested translation."))
+			searchers = append(searchers, newSearcher(translation))
 		}
+		if len(searchers) == 0 {
+			cobra.CheckErr(errors.New("No translation given"))
+		}
+		key := strings.Join(names, ",")
 
-		var searcher search.Searcher
-		if local && !viper.GetBool("force-remote") {
-			searcher, err = search.NewLocal(translation)
+		// The store is optional, so passages can still be read without it
+		s, err := store.Open()
+		if err == nil {
+			defer s.Close()
+		}
+
+		var yoffset int
+		if len(args) == 0 || viper.GetBool("resume") {
+			if s == nil {
+				cobra.CheckErr(errors.Join(errors.New("Unable to resume"), err))
+			}
+
+			position, ok, err := s.Position(key)
 			cobra.CheckErr(err)
-		} else {
-			searcher = search.NewRemote(translation)
+			if ok {
+				query = position.Query
+				yoffset = position.YOffset
+			} else if len(args) == 0 {
+				cobra.CheckErr(fmt.Errorf("No query given and nothing to resume for %s", key))
+			}
+		}
+
+		format, err := reader.ParseFormat(cmd.Flag("format").Value.String())
+		cobra.CheckErr(err)
+
+		if viper.GetBool("print") || cmd.Flags().Changed("format") || !interactive() {
+			cobra.CheckErr(printPassage(cmd.Context(), searchers, query, format, padding, wrap, redletter))
+			return
 		}
 
-		r := reader.NewReader(searcher, query)
+		r := reader.NewReader(searchers, query)
 		r.SetPadding(padding)
 		r.SetWrap(wrap)
+		r.SetRedLetter(redletter)
+		r.SetYOffset(yoffset)
+		if s != nil {
+			r.SetStore(s)
+		}
 
 		p := tea.NewProgram(r, tea.WithMouseCellMotion(), tea.WithAltScreen())
 		p.SetWindowTitle(query)
@@ -56,11 +103,20 @@ var root = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 			os.Exit(1)
 		}
+
+		if query, yoffset, ok := r.Position(); ok && s != nil {
+			cobra.CheckErr(s.SavePosition(key, query, yoffset))
+		}
 	},
 }
 
 func Execute() {
-	err := root.Execute()
+	// Interrupting stops any request in flight rather than leaving it to
+	// hang, giving commands the chance to clean up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := root.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -69,11 +125,27 @@ func Execute() {
 func init() {
 	var config string
 	root.PersistentFlags().StringVarP(&config, "config", "c", "~/.config/bgate/config.json", "Config file to use.")
-	root.Flags().StringP("translation", "t", "ESV", "The translation of the Bible to search for.")
+	root.PersistentFlags().Bool("debug", false, "Save pages from BibleGateway that can't be understood to ~/.bgate/debug, to attach to bug reports.")
+	viper.BindPFlag("debug", root.PersistentFlags().Lookup("debug"))
+	root.PersistentFlags().Duration("timeout", search.DefaultTimeout, "How long to wait for BibleGateway before giving up on a request, or 0 to wait forever.")
+	viper.BindPFlag("timeout", root.PersistentFlags().Lookup("timeout"))
+	root.PersistentFlags().StringSlice("sources", defaultSources, "Where to look for passages, in order, moving on to the next when one fails or doesn't have the passage. Sources are local, cache and remote.")
+	viper.BindPFlag("sources", root.PersistentFlags().Lookup("sources"))
+	root.PersistentFlags().Duration("cache-ttl", search.DefaultCacheTTL, "How long passages from BibleGateway are cached before being fetched again.")
+	viper.BindPFlag("cache-ttl", root.PersistentFlags().Lookup("cache-ttl"))
+	root.PersistentFlags().Int64("cache-size", search.DefaultCacheSize>>20, "Size of the cache in megabytes, or 0 to not cache passages.")
+	viper.BindPFlag("cache-size", root.PersistentFlags().Lookup("cache-size"))
+	root.Flags().StringP("translation", "t", "ESV", "The translation of the Bible to search for. Separate several with commas to read them side by side.")
+	root.RegisterFlagCompletionFunc("translation", completeTranslations)
 	root.Flags().IntP("padding", "p", 0, "Horizontal padding in character count.")
 	root.Flags().BoolP("wrap", "w", false, "Wrap verses, this will cause it to not start each verse on a new line.")
-	root.Flags().Bool("force-local", false, "Force the program to crash if there isn't a local copy of the translation you're trying to read.")
-	root.Flags().Bool("force-remote", false, "Force the program to use the remote searcher even if there is a local copy of the translation.")
+	root.Flags().Bool("force-local", false, "Force the program to crash if there isn't a local copy of the translation you're trying to read. Short for --sources local.")
+	root.Flags().Bool("force-remote", false, "Force the program to use the remote searcher even if there is a local copy of the translation. Short for --sources cache,remote.")
+	root.MarkFlagsMutuallyExclusive("force-local", "force-remote", "sources")
+	root.Flags().StringP("format", "f", "text", "Print the passage as text, json, markdown or html rather than opening the interactive reader.")
+	root.Flags().Bool("resume", false, "Open the last passage read in the translation where it was left, ignoring any query.")
+	root.Flags().Bool("red-letter", true, "Show the words of Jesus in red. Use --red-letter=false to show them like the rest of the text.")
+	root.Flags().Bool("print", false, "Print the passage and exit rather than opening the interactive reader. This is the default when output isn't a terminal.")
 
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nilptrderef/bgate/search"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newRemote searches BibleGateway for translation, configured from the flags
+// and config shared by every command.
+func newRemote(translation string) *search.Remote {
+	remote := search.NewRemote(translation)
+	remote.SetTimeout(viper.GetDuration("timeout"))
+	remote.SetDebug(viper.GetBool("debug"))
+	return remote
+}
+
+// withTimeout limits ctx to the configured timeout for requests, if any.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := viper.GetDuration("timeout")
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// sharedCache opens the cache once for every translation read.
+var sharedCache = sync.OnceValues(openCache)
+
+// searchCache returns the cache shared by every translation, or nil if it's
+// turned off or can't be opened.
+func searchCache() *search.Cache {
+	if cacheSize() <= 0 {
+		return nil
+	}
+
+	c, err := sharedCache()
+	if err != nil {
+		warnCache.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: unable to open the cache: %v\n", err)
+		})
+		return nil
+	}
+	return c
+}
+
+var warnCache sync.Once
+
+// defaultSources looks for passages in a downloaded copy first, then in the
+// cache, and only then reaches out to BibleGateway.
+var defaultSources = []string{"local", "cache", "remote"}
+
+// sources returns where to look for passages, in order.
+func sources() []string {
+	switch {
+	case viper.GetBool("force-local"):
+		return []string{"local"}
+	case viper.GetBool("force-remote"):
+		return []string{"cache", "remote"}
+	}
+
+	var names []string
+	for _, name := range viper.GetStringSlice("sources") {
+		names = append(names, strings.ToLower(strings.TrimSpace(name)))
+	}
+	return names
+}
+
+// newSearcher chains together the configured sources for translation.
+// Sources that aren't available, such as a local copy that hasn't been
+// downloaded, are left out.
+func newSearcher(translation string) search.Searcher {
+	names := sources()
+
+	var links []search.Searcher
+	var local bool
+	for _, name := range names {
+		switch name {
+		case "local":
+			ok, err := search.TranslationHasLocal(translation)
+			cobra.CheckErr(err)
+			if !ok {
+				continue
+			}
+
+			searcher, err := search.NewLocal(translation)
+			cobra.CheckErr(err)
+			links = append(links, searcher)
+			local = true
+		case "cache":
+			if c := searchCache(); c != nil {
+				links = append(links, c.Searcher(translation))
+			}
+		case "remote":
+			// A translation that has been downloaded is known to exist. Only
+			// the cached list of versions is checked so that the reader
+			// never waits on BibleGateway to open, otherwise the remote
+			// checks it itself once a query fails.
+			if versions, ok := search.CachedVersions(); ok && !local {
+				cobra.CheckErr(search.CheckVersion(versions, translation))
+			}
+
+			remote := newRemote(translation)
+			if c := searchCache(); c != nil && slices.Contains(names, "cache") {
+				links = append(links, search.NewCached(remote, c))
+			} else {
+				links = append(links, remote)
+			}
+		default:
+			cobra.CheckErr(fmt.Errorf("Unknown source %q, sources can be local, cache and remote", name))
+		}
+	}
+
+	switch len(links) {
+	case 0:
+		if slices.Equal(names, []string{"local"}) {
+			cobra.CheckErr(fmt.Errorf("No local copy of %s found. Please use download command for requested translation.", translation))
+		}
+		cobra.CheckErr(fmt.Errorf("None of the sources %s are available for %s", strings.Join(names, ","), translation))
+	case 1:
+		return links[0]
+	}
+	return search.NewChain(translation, links...)
+}
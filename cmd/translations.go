@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+	"github.com/spf13/cobra"
+)
+
+var translations = &cobra.Command{
+	Use:   "translations",
+	Short: "Manage downloaded translations and list available ones",
+}
+
+var translationsLocal = &cobra.Command{
+	Use:   "local",
+	Short: "List downloaded translations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		locals, err := search.LocalTranslations()
+		cobra.CheckErr(err)
+
+		if len(locals) == 0 {
+			fmt.Println("No translations downloaded.")
+			return
+		}
+
+		for _, local := range locals {
+			if local.Err != nil {
+				fmt.Printf("%-10s %8s unreadable: %v\n", local.Name, humanSize(local.Size), local.Err)
+				continue
+			}
+			fmt.Printf("%-10s %8s %6d verses  %s  %s\n", local.Name, humanSize(local.Size), local.Verses, local.Downloaded.Format("2006-01-02"), local.Title)
+		}
+	},
+}
+
+var translationsRemote = &cobra.Command{
+	Use:   "remote",
+	Short: "List the translations available from BibleGateway",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		language, _ := cmd.Flags().GetString("language")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+
+		versions, err := versions(cmd.Context(), refresh)
+		cobra.CheckErr(err)
+
+		var current string
+		for _, version := range versions {
+			if !strings.Contains(strings.ToLower(version.Language), strings.ToLower(language)) {
+				continue
+			}
+			if version.Language != current {
+				if current != "" {
+					fmt.Println()
+				}
+				current = version.Language
+				fmt.Println(model.BookStyle.Render(current))
+			}
+			fmt.Printf("  %-12s %s\n", version.Code, version.Name)
+		}
+	},
+}
+
+var translationsRemove = &cobra.Command{
+	Use:     "rm <translation>...",
+	Aliases: []string{"remove"},
+	Short:   "Delete downloaded translations",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, translation := range args {
+			cobra.CheckErr(search.RemoveLocal(translation))
+			fmt.Printf("Removed %s\n", translation)
+		}
+	},
+}
+
+var translationsVerify = &cobra.Command{
+	Use:   "verify <translation>",
+	Short: "Check a downloaded translation for missing or empty chapters",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		translation := args[0]
+
+		local, err := search.TranslationHasLocal(translation)
+		cobra.CheckErr(err)
+		if !local {
+			cobra.CheckErr(fmt.Errorf("No local copy of %s found", translation))
+		}
+
+		searcher, err := search.NewLocal(translation)
+		cobra.CheckErr(err)
+		defer searcher.Close()
+
+		problems, err := searcher.Verify(cmd.Context())
+		cobra.CheckErr(err)
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			cobra.CheckErr(errors.New("Verification failed, download the translation again to repair it"))
+		}
+		fmt.Printf("%s is complete\n", translation)
+	},
+}
+
+// versions lists the translations available from BibleGateway, within the
+// configured timeout if they need fetching.
+func versions(ctx context.Context, refresh bool) ([]search.Version, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return search.Versions(ctx, newRemote(""), refresh)
+}
+
+// validateTranslation fails for translations BibleGateway doesn't offer, for
+// commands about to reach out to it.
+func validateTranslation(ctx context.Context, remote *search.Remote) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	cobra.CheckErr(remote.Validate(ctx))
+}
+
+// completeTranslations offers downloaded translations and those available
+// from BibleGateway, after the last comma for flags that take several.
+func completeTranslations(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	var completions []string
+	seen := map[string]bool{}
+	add := func(code string, description string) {
+		if seen[strings.ToLower(code)] || !strings.HasPrefix(strings.ToLower(code), strings.ToLower(toComplete)) {
+			return
+		}
+		seen[strings.ToLower(code)] = true
+		completions = append(completions, fmt.Sprintf("%s%s\t%s", prefix, code, description))
+	}
+
+	if locals, err := search.LocalTranslations(); err == nil {
+		for _, local := range locals {
+			add(local.Name, strings.TrimSpace(local.Title+" (downloaded)"))
+		}
+	}
+	if versions, err := versions(cmd.Context(), false); err == nil {
+		for _, version := range versions {
+			add(version.Code, version.Name)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+func init() {
+	translationsRemote.Flags().StringP("language", "l", "", "Only list translations in languages matching this. (Case insensitive)")
+	translationsRemote.Flags().Bool("refresh", false, "Fetch the list again rather than using the cached copy.")
+	translations.AddCommand(translationsLocal)
+	translations.AddCommand(translationsRemote)
+	translations.AddCommand(translationsRemove)
+	translations.AddCommand(translationsVerify)
+	root.AddCommand(translations)
+}
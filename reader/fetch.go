@@ -0,0 +1,210 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// passageMsg carries the result of a fetch back to the reader. The id is used
+// to drop results that were cancelled or replaced by a newer fetch.
+type passageMsg struct {
+	id       int
+	query    string
+	verses   []model.Verse
+	parallel [][]model.Verse
+	books    []model.Book
+	hits     bool
+	err      error
+
+	// Set when only the booklist was loaded, for the picker
+	picker bool
+
+	// Verse to scroll to once the passage is shown
+	target search.Reference
+
+	// Set when going back or forward through the history rather than
+	// visiting somewhere new
+	revisit bool
+	visit   int
+	yoffset int
+}
+
+// fetch starts f in the background and shows the spinner until its result
+// arrives. Any fetch already in flight is cancelled.
+func (r *Reader) fetch(description string, f func(ctx context.Context) passageMsg) tea.Cmd {
+	r.cancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelfetch = cancel
+
+	r.fetchid++
+	id := r.fetchid
+	r.loading = description
+
+	return tea.Batch(
+		func() tea.Msg {
+			msg := f(ctx)
+			msg.id = id
+			return msg
+		},
+		r.spinner.Tick,
+	)
+}
+
+// cancel stops the fetch in flight, if any. Its result is dropped when it
+// arrives.
+func (r *Reader) cancel() {
+	if r.cancelfetch != nil {
+		r.cancelfetch()
+		r.cancelfetch = nil
+	}
+	r.fetchid++
+	r.loading = ""
+}
+
+func (r *Reader) fetchQuery(query string) tea.Cmd {
+	searchers := r.searchers
+	return r.fetch(query, func(ctx context.Context) passageMsg {
+		return querymsg(ctx, searchers, query)
+	})
+}
+
+// fetchReference opens the whole chapter a reference is in, scrolled so that
+// the referenced verse is at the top.
+func (r *Reader) fetchReference(reference search.Reference) tea.Cmd {
+	searchers := r.searchers
+	query := fmt.Sprintf("%s %d", reference.Book, reference.Chapter)
+	return r.fetch(query, func(ctx context.Context) passageMsg {
+		msg := querymsg(ctx, searchers, query)
+		msg.target = reference
+		return msg
+	})
+}
+
+func (r *Reader) fetchGrep(words string) tea.Cmd {
+	searchers := r.searchers
+	return r.fetch(words, func(ctx context.Context) passageMsg {
+		return grepmsg(ctx, searchers, words)
+	})
+}
+
+// fetchVisit goes back to an earlier visit in the history, scrolled to where
+// it was left.
+func (r *Reader) fetchVisit(index int) tea.Cmd {
+	searchers := r.searchers
+	v := r.history[index]
+	return r.fetch(v.query, func(ctx context.Context) passageMsg {
+		var msg passageMsg
+		if v.hits {
+			msg = grepmsg(ctx, searchers, v.query)
+		} else {
+			msg = querymsg(ctx, searchers, v.query)
+		}
+		msg.revisit = true
+		msg.visit = index
+		msg.yoffset = v.yoffset
+		return msg
+	})
+}
+
+func querymsg(ctx context.Context, searchers []search.Searcher, query string) passageMsg {
+	verses, parallel, err := querypassage(ctx, searchers, query)
+	return passageMsg{query: query, verses: verses, parallel: parallel, err: err}
+}
+
+func grepmsg(ctx context.Context, searchers []search.Searcher, words string) passageMsg {
+	grepper, ok := searchers[0].(search.Grepper)
+	if !ok {
+		return passageMsg{err: search.ErrNoGrepper}
+	}
+
+	verses, err := grepper.Grep(ctx, words, search.BookRange{}, 100)
+	return passageMsg{query: words, verses: verses, hits: true, err: err}
+}
+
+// fetchAdjacent loads the chapter before or after the current passage,
+// loading the booklist first if it hasn't been yet.
+func (r *Reader) fetchAdjacent(next bool) tea.Cmd {
+	searchers := r.searchers
+	books := r.books
+	verse := r.verses[0]
+	if next {
+		verse = r.verses[len(r.verses)-1]
+	}
+
+	return r.fetch("chapter", func(ctx context.Context) passageMsg {
+		if books == nil {
+			var err error
+			books, err = searchers[0].Booklist(ctx)
+			if err != nil {
+				return passageMsg{err: err}
+			}
+		}
+
+		query, err := adjacentchapter(books, verse, next)
+		if err != nil {
+			return passageMsg{books: books, err: err}
+		}
+
+		verses, parallel, err := querypassage(ctx, searchers, query)
+		return passageMsg{query: query, verses: verses, parallel: parallel, books: books, err: err}
+	})
+}
+
+// querypassage looks up query in every translation, the first of which is the
+// primary one.
+func querypassage(ctx context.Context, searchers []search.Searcher, query string) ([]model.Verse, [][]model.Verse, error) {
+	verses, err := searchers[0].Query(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parallel := make([][]model.Verse, len(searchers)-1)
+	for i, searcher := range searchers[1:] {
+		parallel[i], err = searcher.Query(ctx, query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", searcher.Translation(), err)
+		}
+	}
+
+	return verses, parallel, nil
+}
+
+// adjacentchapter returns the query for the chapter before or after the one
+// verse is in, wrapping around at either end of the Bible.
+func adjacentchapter(books []model.Book, verse model.Verse, next bool) (string, error) {
+	index := slices.IndexFunc(books, func(b model.Book) bool {
+		return b.Name == verse.Book
+	})
+	if index == -1 {
+		return "", errors.New("error finding current book in booklist: not found")
+	}
+
+	book := verse.Book
+	chapter := verse.Chapter
+	if next {
+		// Handle being end of book
+		if chapter == books[index].Chapters {
+			book = books[(index+1)%len(books)].Name
+			chapter = 0
+		}
+		chapter++
+	} else {
+		// Handle being beginning of book
+		if chapter == 1 {
+			index = (index - 1 + len(books)) % len(books)
+			book = books[index].Name
+			chapter = books[index].Chapters + 1
+		}
+		chapter--
+	}
+
+	return book + " " + strconv.Itoa(chapter), nil
+}
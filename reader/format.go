@@ -0,0 +1,190 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+var Formats = []Format{FormatText, FormatJSON, FormatMarkdown, FormatHTML}
+
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(s, string(format)) {
+			return format, nil
+		}
+	}
+	if strings.EqualFold(s, "md") {
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("Unknown format %q, expected one of text, json, markdown or html", s)
+}
+
+// WriteVerses writes a passage to w in the given format. Width and wrap follow
+// the same rules as RenderVerses for text, while markdown and html only use
+// wrap to decide whether verses share a paragraph. Lines of poetry are broken
+// and indented in every format. Unless redletter is set, the words of Jesus
+// are left unmarked.
+func WriteVerses(w io.Writer, verses []model.Verse, format Format, width int, wrap bool, redletter bool) error {
+	verses = redLetters(verses, redletter)
+	switch format {
+	case FormatText:
+		_, err := fmt.Fprintln(w, RenderVerses(verses, width, wrap, true))
+		return err
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if verses == nil {
+			verses = []model.Verse{}
+		}
+		return encoder.Encode(verses)
+	case FormatMarkdown:
+		_, err := io.WriteString(w, renderMarkdown(verses, wrap))
+		return err
+	case FormatHTML:
+		_, err := io.WriteString(w, renderHTML(verses, wrap))
+		return err
+	}
+	return fmt.Errorf("Unknown format %q", format)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+func renderMarkdown(verses []model.Verse, wrap bool) string {
+	var writer strings.Builder
+	paragraph := false
+	closeparagraph := func() {
+		if paragraph {
+			writer.WriteString("\n\n")
+			paragraph = false
+		}
+	}
+
+	for i, verse := range verses {
+		if verse.Number == 1 && verse.Part == 1 {
+			closeparagraph()
+			writer.WriteString(fmt.Sprintf("# %s %d\n\n", verse.Book, verse.Chapter))
+		}
+
+		if verse.HasTitle() {
+			closeparagraph()
+			writer.WriteString("## " + markdownEscaper.Replace(*verse.Title) + "\n\n")
+		}
+
+		if (!wrap && verse.Part == 1) || verse.Paragraph {
+			closeparagraph()
+		}
+
+		if paragraph && (verse.Poetry || verses[i-1].Poetry) {
+			writer.WriteString("  \n")
+		} else if paragraph {
+			writer.WriteString(" ")
+		}
+		if verse.Poetry {
+			writer.WriteString(strings.Repeat("&emsp;", verse.Indent))
+		}
+		if verse.Part == 1 {
+			writer.WriteString(fmt.Sprintf("<sup>%d</sup> ", verse.Number))
+		}
+		writer.WriteString(spansHTML(verse, markdownEscaper.Replace))
+		paragraph = true
+	}
+	if paragraph {
+		writer.WriteString("\n")
+	}
+
+	return writer.String()
+}
+
+func renderHTML(verses []model.Verse, wrap bool) string {
+	var writer strings.Builder
+	paragraph := false
+	closeparagraph := func() {
+		if paragraph {
+			writer.WriteString("</p>\n")
+			paragraph = false
+		}
+	}
+
+	for i, verse := range verses {
+		if verse.Number == 1 && verse.Part == 1 {
+			closeparagraph()
+			writer.WriteString(fmt.Sprintf("<h1 class=\"chapter\">%s %d</h1>\n", html.EscapeString(verse.Book), verse.Chapter))
+		}
+
+		if verse.HasTitle() {
+			closeparagraph()
+			writer.WriteString("<h2 class=\"title\">" + html.EscapeString(*verse.Title) + "</h2>\n")
+		}
+
+		if (!wrap && verse.Part == 1) || verse.Paragraph {
+			closeparagraph()
+		}
+
+		if paragraph && (verse.Poetry || verses[i-1].Poetry) {
+			writer.WriteString("<br>\n")
+		} else if paragraph {
+			writer.WriteString(" ")
+		} else {
+			writer.WriteString("<p>")
+			paragraph = true
+		}
+		if verse.Poetry {
+			writer.WriteString(strings.Repeat("&emsp;", verse.Indent))
+		}
+		if verse.Part == 1 {
+			writer.WriteString(fmt.Sprintf("<sup class=\"versenum\">%d</sup> ", verse.Number))
+		}
+		writer.WriteString(spansHTML(verse, html.EscapeString))
+	}
+	closeparagraph()
+
+	return writer.String()
+}
+
+// spansHTML renders the text of a verse, escaped with escape, with its spans as
+// inline HTML, which markdown allows as well.
+func spansHTML(verse model.Verse, escape func(string) string) string {
+	var writer strings.Builder
+	for _, segment := range verse.Segments() {
+		if segment.Note != nil {
+			continue
+		}
+
+		text := escape(segment.Text)
+		for _, kind := range segment.Kinds {
+			switch kind {
+			case model.WordsOfJesus:
+				text = `<span class="woj">` + text + "</span>"
+			case model.SmallCaps:
+				text = `<span class="small-caps">` + text + "</span>"
+			case model.Italic:
+				text = "<i>" + text + "</i>"
+			}
+		}
+		writer.WriteString(text)
+	}
+	return strings.TrimSpace(writer.String())
+}
@@ -0,0 +1,46 @@
+package reader
+
+// maxhistory is how many visits the reader remembers before dropping the
+// oldest.
+const maxhistory = 100
+
+// visit is a passage, or a search by words, in the reader's history along
+// with how far down it was read.
+type visit struct {
+	query   string
+	hits    bool
+	yoffset int
+}
+
+// remember adds a newly opened passage to the history after the current one,
+// dropping anything that could have been gone forward to.
+func (r *Reader) remember(v visit) {
+	if len(r.history) > 0 {
+		r.history[r.historyindex].yoffset = r.viewport.YOffset
+		r.history = r.history[:r.historyindex+1]
+	}
+
+	r.history = append(r.history, v)
+	if len(r.history) > maxhistory {
+		r.history = r.history[len(r.history)-maxhistory:]
+	}
+	r.historyindex = len(r.history) - 1
+}
+
+// historyEntries lists the history for the overlay, most recent first.
+func (r *Reader) historyEntries() []entry {
+	entries := make([]entry, 0, len(r.history))
+	for i := len(r.history) - 1; i >= 0; i-- {
+		v := r.history[i]
+
+		e := entry{title: v.query, id: i}
+		if v.hits {
+			e.title = "Search: " + v.query
+		}
+		if i == r.historyindex {
+			e.description = "Current"
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -2,18 +2,165 @@ package model
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/nilptrderef/bgate/reader/style"
 )
 
 type Verse struct {
-	Book    string  `db:"book"`
-	Chapter int     `db:"chapter"`
-	Number  int     `db:"number"`
-	Part    int     `db:"part"`
-	Text    string  `db:"text"`
-	Title   *string `db:"title"`
+	Book    string  `db:"book" json:"book"`
+	Chapter int     `db:"chapter" json:"chapter"`
+	Number  int     `db:"number" json:"number"`
+	Part    int     `db:"part" json:"part"`
+	Text    string  `db:"text" json:"text"`
+	Title   *string `db:"title" json:"title"`
+	Notes   []Note  `db:"-" json:"notes,omitempty"`
+	Spans   []Span  `db:"-" json:"spans,omitempty"`
+
+	// Paragraph is set on the part that starts a paragraph, or a stanza of
+	// poetry. Poetry parts are each a line of their own, indented by Indent
+	// levels.
+	Paragraph bool `db:"paragraph" json:"paragraph,omitempty"`
+	Poetry    bool `db:"poetry" json:"poetry,omitempty"`
+	Indent    int  `db:"indent" json:"indent,omitempty"`
+}
+
+const (
+	Footnote       = "footnote"
+	CrossReference = "crossref"
+)
+
+// Note is a footnote or cross-reference on a verse. Its marker belongs Offset
+// bytes into the verse's text. For cross-references, References is the query
+// for the passages referred to.
+type Note struct {
+	Kind       string `db:"kind" json:"kind"`
+	Marker     string `db:"marker" json:"marker"`
+	Offset     int    `db:"position" json:"offset"`
+	Text       string `db:"text" json:"text"`
+	References string `db:"refs" json:"references,omitempty"`
+}
+
+const (
+	WordsOfJesus = "woj"
+	SmallCaps    = "small-caps"
+	Italic       = "italic"
+)
+
+// Span marks Length bytes of a verse's text, starting Offset bytes in, as
+// styled, such as the words of Jesus or words supplied by the translators.
+type Span struct {
+	Kind   string `db:"kind" json:"kind"`
+	Offset int    `db:"position" json:"offset"`
+	Length int    `db:"length" json:"length"`
+}
+
+// Segment is a piece of a verse's text styled by every kind of span in Kinds,
+// or the marker of a note when Note is set.
+type Segment struct {
+	Text  string
+	Kinds []string
+	Note  *Note
+}
+
+func (n Note) MarkerString() string {
+	if n.Kind == CrossReference {
+		return style.NoteStyle.Render("(" + n.Marker + ")")
+	}
+	return style.NoteStyle.Render("[" + n.Marker + "]")
+}
+
+// WithoutSpans returns the verse with none of its spans of kind.
+func (v Verse) WithoutSpans(kind string) Verse {
+	v.Spans = slices.DeleteFunc(slices.Clone(v.Spans), func(s Span) bool {
+		return s.Kind == kind
+	})
+	return v
+}
+
+// Segments splits the text of the verse wherever a span starts or ends, with
+// the markers of its notes in place.
+func (v Verse) Segments() []Segment {
+	bounds := []int{0, len(v.Text)}
+	for _, span := range v.Spans {
+		bounds = append(bounds, span.Offset, span.Offset+span.Length)
+	}
+	for _, note := range v.Notes {
+		bounds = append(bounds, note.Offset)
+	}
+	for i := range bounds {
+		bounds[i] = min(max(bounds[i], 0), len(v.Text))
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var segments []Segment
+	var note int
+	for i, start := range bounds {
+		for ; note < len(v.Notes) && min(max(v.Notes[note].Offset, 0), len(v.Text)) <= start; note++ {
+			segments = append(segments, Segment{Note: &v.Notes[note]})
+		}
+		if i == len(bounds)-1 {
+			break
+		}
+
+		segment := Segment{Text: v.Text[start:bounds[i+1]]}
+		for _, span := range v.Spans {
+			if span.Offset <= start && start < span.Offset+span.Length && !slices.Contains(segment.Kinds, span.Kind) {
+				segment.Kinds = append(segment.Kinds, span.Kind)
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// MarkedText is the text of the verse styled by its spans, with the markers of
+// its notes in place.
+func (v Verse) MarkedText() string {
+	if len(v.Notes) == 0 && len(v.Spans) == 0 {
+		return v.Text
+	}
+
+	var writer strings.Builder
+	for _, segment := range v.Segments() {
+		if segment.Note != nil {
+			writer.WriteString(segment.Note.MarkerString())
+		} else {
+			writer.WriteString(segment.String())
+		}
+	}
+	return writer.String()
+}
+
+// String renders the segment in the styles of its kinds. Each word is styled
+// separately so that the styling survives the text being wrapped.
+func (s Segment) String() string {
+	if len(s.Kinds) == 0 {
+		return s.Text
+	}
+
+	segmentstyle := lipgloss.NewStyle()
+	for _, kind := range s.Kinds {
+		switch kind {
+		case WordsOfJesus:
+			segmentstyle = segmentstyle.Inherit(style.WordsOfJesusStyle)
+		case SmallCaps:
+			segmentstyle = segmentstyle.Inherit(style.SmallCapsStyle)
+		case Italic:
+			segmentstyle = segmentstyle.Inherit(style.ItalicStyle)
+		}
+	}
+
+	words := strings.Split(s.Text, " ")
+	for i, word := range words {
+		if word != "" {
+			words[i] = segmentstyle.Render(word)
+		}
+	}
+	return strings.Join(words, " ")
 }
 
 func (v Verse) HasTitle() bool {
@@ -34,6 +181,11 @@ func (v Verse) NumberString() string {
 	return style.NumberStyle.Render(text)
 }
 
+func (v Verse) ReferenceString() string {
+	text := fmt.Sprintf("%s %d:%d", v.Book, v.Chapter, v.Number)
+	return style.NumberStyle.Render(text)
+}
+
 var BookStyle = lipgloss.NewStyle().
 	Bold(true).
 	Foreground(lipgloss.Color("#6A7FDB"))
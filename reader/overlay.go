@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/reader/style"
+	"github.com/nilptrderef/bgate/search"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// entry is a row in one of the reader's overlay lists, pointing at the
+// passage, note or book to open when it is chosen.
+type entry struct {
+	title       string
+	description string
+	reference   search.Reference
+	id          int
+	note        model.Note
+	book        model.Book
+
+	// Headings group the entries below them and can't be chosen
+	header bool
+}
+
+func (e entry) Title() string       { return e.title }
+func (e entry) Description() string { return e.description }
+func (e entry) FilterValue() string {
+	if e.header {
+		return ""
+	}
+	if e.book.Name != "" {
+		return e.book.Name
+	}
+	return e.title + " " + e.description
+}
+
+// openOverlay replaces the passage with a filterable list of entries until it
+// is closed again with esc.
+func (r *Reader) openOverlay(m mode, title string, entries []entry) {
+	items := make([]list.Item, len(entries))
+	for i, e := range entries {
+		items[i] = e
+	}
+
+	r.overlay = list.New(items, list.NewDefaultDelegate(), r.viewport.Width-(2*r.padding), r.viewport.Height)
+	r.overlay.Title = title
+	r.overlay.Styles.Title = style.HeaderStyle
+	r.overlay.SetShowHelp(false)
+	r.overlay.KeyMap.Quit.SetEnabled(false)
+	r.overlay.KeyMap.ForceQuit.SetEnabled(false)
+	r.mode = m
+}
+
+// updateOverlay handles the keys shared by every overlay, returning the chosen
+// entry once enter is pressed.
+func (r *Reader) updateOverlay(msg tea.KeyMsg) (*entry, tea.Cmd) {
+	if r.overlay.FilterState() == list.Unfiltered {
+		switch msg.String() {
+		case "esc", "q":
+			r.mode = read
+			return nil, nil
+		}
+	}
+
+	if r.overlay.FilterState() != list.Filtering && msg.String() == "enter" {
+		r.mode = read
+		if e, ok := r.overlay.SelectedItem().(entry); ok {
+			return &e, nil
+		}
+		return nil, nil
+	}
+
+	var cmd tea.Cmd
+	r.overlay, cmd = r.overlay.Update(msg)
+	return nil, cmd
+}
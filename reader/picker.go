@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/reader/style"
+	"github.com/nilptrderef/bgate/search"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// fetchBooks loads the booklist in the background, opening the picker once it
+// arrives.
+func (r *Reader) fetchBooks() tea.Cmd {
+	searchers := r.searchers
+	return r.fetch("books", func(ctx context.Context) passageMsg {
+		books, err := searchers[0].Booklist(ctx)
+		return passageMsg{books: books, picker: true, err: err}
+	})
+}
+
+// openBooks lists every book under the testament it belongs to, with the book
+// being read selected. The booklist's own order is kept, with a header wherever
+// the testament changes.
+func (r *Reader) openBooks() {
+	var entries []entry
+	header, count := -1, 0
+	for _, book := range r.books {
+		testament := search.Testament(book.Name)
+		if testament == "" {
+			testament = "Apocrypha"
+		}
+		if header == -1 || entries[header].title != testament {
+			header, count = len(entries), 0
+			entries = append(entries, entry{title: testament, header: true})
+		}
+		count++
+		entries[header].description = fmt.Sprintf("%d books", count)
+		entries = append(entries, entry{title: book.Name, description: fmt.Sprintf("%d chapters", book.Chapters), book: book})
+	}
+
+	r.openOverlay(picking, "Books (enter: chapters, /: filter)", entries)
+	if len(r.verses) > 0 && !r.hits {
+		index := slices.IndexFunc(entries, func(e entry) bool {
+			return e.book.Name == r.verses[0].Book
+		})
+		if index != -1 {
+			r.overlay.Select(index)
+		}
+	}
+}
+
+// openChapters shows the chapter grid of book, starting at the chapter being
+// read if it's in that book.
+func (r *Reader) openChapters(book model.Book) {
+	r.pickbook = book
+	r.pickchapter = 1
+	if len(r.verses) > 0 && !r.hits && r.verses[0].Book == book.Name {
+		r.pickchapter = min(max(r.verses[0].Chapter, 1), book.Chapters)
+	}
+	r.mode = chapters
+}
+
+// chapterCells returns the width of each cell of the chapter grid and how
+// many fit on a row.
+func (r *Reader) chapterCells() (int, int) {
+	cellwidth := len(strconv.Itoa(r.pickbook.Chapters)) + 2
+	columns := min(10, max(1, (r.viewport.Width-(2*r.padding))/cellwidth))
+	return cellwidth, columns
+}
+
+func (r *Reader) updateChapters(msg tea.KeyMsg) tea.Cmd {
+	_, columns := r.chapterCells()
+	switch msg.String() {
+	case "esc", "backspace":
+		r.mode = picking
+	case "q":
+		r.mode = read
+	case "left", "h":
+		r.pickchapter = max(1, r.pickchapter-1)
+	case "right", "l":
+		r.pickchapter = min(r.pickbook.Chapters, r.pickchapter+1)
+	case "up", "k":
+		if r.pickchapter-columns >= 1 {
+			r.pickchapter -= columns
+		}
+	case "down", "j":
+		if r.pickchapter+columns <= r.pickbook.Chapters {
+			r.pickchapter += columns
+		}
+	case "home", "g":
+		r.pickchapter = 1
+	case "end", "G":
+		r.pickchapter = r.pickbook.Chapters
+	case "enter":
+		r.mode = read
+		return r.fetchQuery(fmt.Sprintf("%s %d", r.pickbook.Name, r.pickchapter))
+	}
+	return nil
+}
+
+// ChaptersView renders the chapter grid of the book being picked.
+func (r *Reader) ChaptersView() string {
+	cellwidth, columns := r.chapterCells()
+	cell := lipgloss.NewStyle().Width(cellwidth).AlignHorizontal(lipgloss.Right).PaddingRight(1)
+
+	var writer strings.Builder
+	writer.WriteString(style.HeaderStyle.Render(r.pickbook.Name) + "\n\n")
+	for chapter := 1; chapter <= r.pickbook.Chapters; chapter++ {
+		number := strconv.Itoa(chapter)
+		if chapter == r.pickchapter {
+			number = style.SelectedStyle.Render(number)
+		}
+		writer.WriteString(cell.Render(number))
+		if chapter%columns == 0 || chapter == r.pickbook.Chapters {
+			writer.WriteString("\n")
+		}
+	}
+	writer.WriteString("\n" + style.NoteStyle.Render("enter: open, esc: back to books, q: close"))
+	return writer.String()
+}
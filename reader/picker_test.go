@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"testing"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestOpenBooksGroupsByTestament(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "Psalm 23")
+	r.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	r.books = []model.Book{
+		{Name: "Genesis", Chapters: 50},
+		{Name: "Psalms", Chapters: 150},
+		{Name: "Song of Songs", Chapters: 8},
+		{Name: "Malachi", Chapters: 4},
+		{Name: "Tobit", Chapters: 14},
+		{Name: "Matthew", Chapters: 28},
+		{Name: "Revelation", Chapters: 22},
+	}
+	r.verses = []model.Verse{{Book: "Matthew", Chapter: 5, Number: 1}}
+	r.openBooks()
+
+	expected := []struct {
+		title       string
+		description string
+		header      bool
+	}{
+		{"Old Testament", "4 books", true},
+		{"Genesis", "50 chapters", false},
+		{"Psalms", "150 chapters", false},
+		{"Song of Songs", "8 chapters", false},
+		{"Malachi", "4 chapters", false},
+		{"Apocrypha", "1 books", true},
+		{"Tobit", "14 chapters", false},
+		{"New Testament", "2 books", true},
+		{"Matthew", "28 chapters", false},
+		{"Revelation", "22 chapters", false},
+	}
+
+	items := r.overlay.Items()
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(items))
+	}
+	for i, item := range items {
+		e := item.(entry)
+		if e.title != expected[i].title || e.description != expected[i].description || e.header != expected[i].header {
+			t.Errorf("Expected entry %d to be %+v, got %+v", i, expected[i], e)
+		}
+		if e.header && e.FilterValue() != "" {
+			t.Errorf("Expected header %q not to be filterable", e.title)
+		}
+	}
+	if r.overlay.Index() != 8 {
+		t.Fatalf("Expected Matthew to be selected, got %d", r.overlay.Index())
+	}
+}
+
+func TestFilterBooksSkipsHeaders(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "John 3")
+	r.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	r.books = []model.Book{
+		{Name: "Genesis", Chapters: 50},
+		{Name: "John", Chapters: 21},
+	}
+	r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	// A blinking cursor would wait on its timer every time a key is typed
+	r.overlay.FilterInput.Cursor.SetMode(cursor.CursorStatic)
+
+	for _, key := range []string{"/", "t", "e", "s", "t"} {
+		_, cmd := r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		runFilter(r, cmd)
+	}
+
+	if visible := r.overlay.VisibleItems(); len(visible) != 0 {
+		t.Fatalf("Expected the testament headers not to match, got %v", visible)
+	}
+}
+
+// runFilter hands the overlay the matches its filter finds, which it works out
+// in a command rather than straight away.
+func runFilter(r *Reader, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			runFilter(r, cmd)
+		}
+	case list.FilterMatchesMsg:
+		r.Update(msg)
+	}
+}
+
+func TestUpdateChaptersEdges(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "Psalm 23")
+	r.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	r.openChapters(model.Book{Name: "Proverbs", Chapters: 23})
+
+	steps := []struct {
+		key     string
+		chapter int
+	}{
+		{"left", 1},
+		{"up", 1},
+		{"down", 11},
+		{"down", 21},
+		{"down", 21},
+		{"right", 22},
+		{"right", 23},
+		{"right", 23},
+		{"up", 13},
+		{"left", 12},
+		{"home", 1},
+		{"end", 23},
+		{"left", 22},
+		{"down", 22},
+	}
+
+	for i, step := range steps {
+		r.updateChapters(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(step.key)})
+		if r.pickchapter != step.chapter {
+			t.Fatalf("Expected %s (step %d) to pick chapter %d, got %d", step.key, i, step.chapter, r.pickchapter)
+		}
+	}
+}
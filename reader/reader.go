@@ -1,8 +1,8 @@
 package reader
 
 import (
+	"context"
 	"fmt"
-	"slices"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -10,7 +10,10 @@ import (
 	"github.com/nilptrderef/bgate/reader/model"
 	"github.com/nilptrderef/bgate/reader/style"
 	"github.com/nilptrderef/bgate/search"
+	"github.com/nilptrderef/bgate/store"
 
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -21,33 +24,77 @@ type mode int
 const (
 	read mode = iota
 	searching
+	grepping
+	jumping
+	marking
+	bookmarking
+	browsing
+	noting
+	footnote
+	picking
+	chapters
 	help
 )
 
 type Reader struct {
-	searcher search.Searcher
-	query    string
-	viewport viewport.Model
-	ready    bool
-	mode     mode
-	wrap     bool
-	padding  int
-
-	verses []model.Verse
-	books  []model.Book
+	// The first searcher is the primary translation, used for navigation and
+	// searching by words, while any others are shown alongside it.
+	searchers []search.Searcher
+	query     string
+	viewport  viewport.Model
+	ready     bool
+	mode      mode
+	wrap      bool
+	redletter bool
+	padding   int
+
+	verses   []model.Verse
+	parallel [][]model.Verse
+	books    []model.Book
+	hits     bool
+
+	// Line each verse starts on in the rendered content
+	offsets []int
 
 	searchbuffer string
+
+	spinner     spinner.Model
+	fetchid     int
+	cancelfetch context.CancelFunc
+	loading     string
+
+	store   *store.Store
+	overlay list.Model
+	status  string
+
+	// Footnote shown in a popup over the passage
+	note entry
+
+	// Book whose chapters are being picked from
+	pickbook    model.Book
+	pickchapter int
+
+	// Verse or offset to scroll to once the passage is shown
+	target  search.Reference
+	yoffset int
+
+	history      []visit
+	historyindex int
 }
 
-func NewReader(searcher search.Searcher, query string) *Reader {
+func NewReader(searchers []search.Searcher, query string) *Reader {
 	return &Reader{
-		searcher: searcher,
-		query:    query,
+		searchers: searchers,
+		query:     query,
+		redletter: true,
+		spinner:   spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(style.SearchStyle)),
+		// Built empty so that it can be resized before it's first opened
+		overlay: list.New(nil, list.NewDefaultDelegate(), 0, 0),
 	}
 }
 
 func (r *Reader) Init() tea.Cmd {
-	return nil
+	return r.fetchQuery(r.query)
 }
 
 func (r *Reader) SetPadding(p int) {
@@ -61,64 +108,156 @@ func (r *Reader) SetWrap(w bool) {
 	r.wrap = w
 }
 
+// SetRedLetter sets whether the words of Jesus are shown in red.
+func (r *Reader) SetRedLetter(red bool) {
+	r.redletter = red
+}
+
+// SetYOffset sets how far down the first passage opens, for picking up where
+// a previous session left off.
+func (r *Reader) SetYOffset(y int) {
+	r.yoffset = y
+}
+
+// Position returns the passage being read and how far it has been scrolled,
+// reporting false when there is no passage, such as after searching by words.
+func (r *Reader) Position() (string, int, bool) {
+	if len(r.verses) == 0 || r.hits {
+		return "", 0, false
+	}
+	return r.query, r.viewport.YOffset, true
+}
+
+// SetStore gives the reader somewhere to keep bookmarks. Without one the
+// bookmark keys only show an error.
+func (r *Reader) SetStore(s *store.Store) {
+	r.store = s
+}
+
+// RenderVerses renders the current passage, recording where each verse starts
+// so that the reader can tell which verse is on screen.
 func (r *Reader) RenderVerses() string {
+	r.offsets = nil
 	if len(r.verses) == 0 {
+		if r.loading != "" {
+			return ""
+		}
 		return style.ErrorStyle.Render(fmt.Sprintf("No results found for %q", r.query))
 	}
 
-	var writer strings.Builder
-	for index, verse := range r.verses {
-		title := verse.HasTitle()
-		chapter := verse.Number == 1 && verse.Part == 1
+	if r.hits {
+		return r.RenderHits()
+	}
 
-		if index > 0 && r.wrap && (title || chapter) {
-			writer.WriteString("\n")
+	var content string
+	if len(r.parallel) > 0 {
+		passages := [][]model.Verse{redLetters(r.verses, r.redletter)}
+		for _, verses := range r.parallel {
+			passages = append(passages, redLetters(verses, r.redletter))
 		}
+		content, r.offsets = layoutParallel(passages, r.viewport.Width-(2*r.padding))
+	} else {
+		content, r.offsets = layoutVerses(redLetters(r.verses, r.redletter), r.viewport.Width-(2*r.padding), r.wrap)
+	}
+	return content
+}
 
-		if title {
-			writer.WriteString(verse.TitleString() + "\n")
-		}
+// RenderHits lists the verses found by a full-text search, each with its own
+// reference since they aren't a continuous passage.
+func (r *Reader) RenderHits() string {
+	var writer strings.Builder
+	for _, verse := range r.verses {
+		writer.WriteString(verse.ReferenceString() + " " + strings.TrimSpace(verse.Text) + "\n")
+	}
 
-		if chapter {
-			writer.WriteString(verse.ChapterString() + "\n")
-		}
+	content, positions := resize(writer.String(), r.viewport.Width-(2*r.padding), "    ")
+	r.offsets = make([]int, len(r.verses))
+	for i := range r.verses {
+		r.offsets[i] = positions[i][0]
+	}
+	return content
+}
 
-		if verse.Part == 1 {
-			writer.WriteString(verse.NumberString())
+// verseAt returns the verse shown on the given line of content, which is the
+// last one to start at or before it.
+func (r *Reader) verseAt(line int) (model.Verse, bool) {
+	index := -1
+	for i, offset := range r.offsets {
+		if offset > line {
+			break
 		}
+		index = i
+	}
+	if index == -1 {
+		if len(r.verses) == 0 {
+			return model.Verse{}, false
+		}
+		index = 0
+	}
+	return r.verses[index], true
+}
 
-		writer.WriteString(verse.Text + " ")
-
-		if !r.wrap {
-			writer.WriteString("\n")
+// parseJump reads a verse to jump to, either as a verse number in chapter or
+// as chapter:verse.
+func parseJump(target string, chapter int) (int, int, error) {
+	if c, v, ok := strings.Cut(target, ":"); ok {
+		cnum, cerr := strconv.Atoi(strings.TrimSpace(c))
+		vnum, verr := strconv.Atoi(strings.TrimSpace(v))
+		if cerr != nil || verr != nil {
+			return 0, 0, fmt.Errorf("Expected a verse or chapter:verse, got %q", target)
 		}
+		return cnum, vnum, nil
 	}
 
-	indentation := "    "
-	if r.wrap {
-		indentation = ""
+	vnum, err := strconv.Atoi(strings.TrimSpace(target))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Expected a verse or chapter:verse, got %q", target)
 	}
-	return ResizeString(writer.String(), r.viewport.Width-(2*r.padding), indentation)
+	return chapter, vnum, nil
 }
 
-func (r *Reader) Query(query string) (string, error) {
-	r.query = query
+// scrollToVerse moves the viewport so that the given verse, along with any
+// heading above it, is at the top. It reports whether the verse was found.
+func (r *Reader) scrollToVerse(chapter int, number int) bool {
+	for i, verse := range r.verses {
+		if verse.Chapter == chapter && verse.Number == number && i < len(r.offsets) {
+			r.viewport.SetYOffset(r.offsets[i])
+			return true
+		}
+	}
+	return false
+}
 
-	var err error
-	r.verses, err = r.searcher.Query(query)
-	if err != nil {
-		return "", err
+// scrollToTarget moves to the verse the passage was opened at, or otherwise to
+// where a previous session left off. The passage can arrive before the size of
+// the terminal is known, so both wait until the viewport is ready.
+func (r *Reader) scrollToTarget() {
+	if !r.ready {
+		return
 	}
 
-	return r.RenderVerses(), nil
+	if r.target.Verse != 0 {
+		r.scrollToVerse(r.target.Chapter, r.target.Verse)
+	} else if r.yoffset > 0 {
+		r.viewport.SetYOffset(r.yoffset)
+	}
+	r.target = search.Reference{}
+	r.yoffset = 0
 }
 
 func (r *Reader) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		r.status = ""
 		if r.mode == read {
 			switch msg.String() {
-			case "esc", "q", "ctrl+c":
+			case "esc":
+				if r.loading != "" {
+					r.cancel()
+					return r, nil
+				}
+				return r, tea.Quit
+			case "q", "ctrl+c":
 				return r, tea.Quit
 			case "g":
 				r.viewport.GotoTop()
@@ -139,115 +278,107 @@ func (r *Reader) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				content := r.RenderVerses()
 				r.viewport.SetContent(content)
 			case "p":
-				r.viewport.YOffset = 0
-
-				if len(r.verses) == 0 {
+				if len(r.verses) == 0 || r.hits {
+					r.viewport.YOffset = 0
 					e := "You must have a selected passage to go to the previous chapter."
 					r.viewport.SetContent(style.ErrorStyle.Render(e))
 					return r, nil
 				}
-
-				first := r.verses[0]
-
-				// Previous chapter
-				chapter := first.Chapter
-
-				if r.books == nil {
-					var err error
-					r.books, err = r.searcher.Booklist()
-					if err != nil {
-						e := err.Error()
-						r.viewport.SetContent(style.ErrorStyle.Render(e))
-						return r, nil
-					}
-				}
-
-				// Handle being beginning of book
-				book := first.Book
-				if chapter == 1 {
-					index := slices.IndexFunc(r.books, func(b model.Book) bool {
-						return b.Name == first.Book
-					})
-					if index == -1 {
-						e := "error finding current book in booklist: not found"
-						r.viewport.SetContent(style.ErrorStyle.Render(e))
-						return r, nil
-					} else if index == 0 {
-						book = r.books[len(r.books)-1].Name
-						chapter = r.books[len(r.books)-1].Chapters + 1
-					} else {
-						book = r.books[index-1].Name
-						chapter = r.books[index-1].Chapters + 1
-					}
-				}
-				content, err := r.Query(book + " " + strconv.Itoa(chapter-1))
-				if err != nil {
-					e := err.Error()
+				return r, r.fetchAdjacent(false)
+			case "n":
+				if len(r.verses) == 0 || r.hits {
+					r.viewport.YOffset = 0
+					e := "You must have a selected passage to go to the next chapter."
 					r.viewport.SetContent(style.ErrorStyle.Render(e))
 					return r, nil
 				}
-
-				r.viewport.SetContent(content)
-				return r, tea.SetWindowTitle(r.query)
-			case "n":
-				r.viewport.YOffset = 0
-
+				return r, r.fetchAdjacent(true)
+			case "/":
+				r.mode = searching
+			case "s":
+				r.mode = grepping
+			case ":":
 				if len(r.verses) == 0 {
-					e := "You must have a selected passage to go to the next chapter."
-					r.viewport.SetContent(style.ErrorStyle.Render(e))
+					r.status = "There is no passage to jump in"
 					return r, nil
 				}
-
-				last := r.verses[len(r.verses)-1]
-
-				// Next chapter
-				chapter := last.Chapter
-
-				if r.books == nil {
-					var err error
-					r.books, err = r.searcher.Booklist()
-					if err != nil {
-						e := err.Error()
-						r.viewport.SetContent(style.ErrorStyle.Render(e))
-						return r, nil
-					}
+				// Search results span many books, so a verse number alone
+				// couldn't say which one is meant
+				if r.hits {
+					r.status = "Jumping to a verse needs a passage, not search results"
+					return r, nil
 				}
-
-				index := slices.IndexFunc(r.books, func(b model.Book) bool {
-					return b.Name == last.Book
-				})
-				if index == -1 {
-					e := "error finding current book in booklist: not found"
-					r.viewport.SetContent(style.ErrorStyle.Render(e))
+				r.mode = jumping
+			case "m":
+				if r.store == nil {
+					r.status = "Bookmarks are unavailable"
 					return r, nil
 				}
-
-				// Handle being end of book
-				book := last.Book
-				if chapter == r.books[index].Chapters {
-					if index == len(r.books)-1 {
-						book = r.books[0].Name
-						chapter = 0
-					} else {
-						book = r.books[index+1].Name
-						chapter = 0
-					}
+				if _, ok := r.verseAt(r.viewport.YOffset); !ok {
+					r.status = "There is no verse to bookmark"
+					return r, nil
+				}
+				r.mode = marking
+			case "'":
+				if r.store == nil {
+					r.status = "Bookmarks are unavailable"
+					return r, nil
 				}
-				content, err := r.Query(book + " " + strconv.Itoa(chapter+1))
+				marks, err := r.store.Bookmarks()
 				if err != nil {
-					e := err.Error()
-					r.viewport.SetContent(style.ErrorStyle.Render(e))
+					r.status = err.Error()
 					return r, nil
 				}
-
-				r.viewport.SetContent(content)
-				return r, tea.SetWindowTitle(r.query)
-			case "/":
-				r.mode = searching
+				entries := make([]entry, len(marks))
+				for i, mark := range marks {
+					entries[i] = entry{
+						title:       mark.Reference().String(),
+						description: strings.TrimSpace(mark.Label + "  " + mark.Created.Local().Format("2006-01-02 15:04")),
+						reference:   mark.Reference(),
+						id:          mark.ID,
+					}
+				}
+				r.openOverlay(bookmarking, "Bookmarks (enter: open, x: remove)", entries)
+				return r, nil
+			case "H":
+				if r.historyindex == 0 || len(r.history) == 0 {
+					r.status = "Nothing further back in history"
+					return r, nil
+				}
+				r.history[r.historyindex].yoffset = r.viewport.YOffset
+				return r, r.fetchVisit(r.historyindex - 1)
+			case "L":
+				if r.historyindex >= len(r.history)-1 {
+					r.status = "Nothing further forward in history"
+					return r, nil
+				}
+				r.history[r.historyindex].yoffset = r.viewport.YOffset
+				return r, r.fetchVisit(r.historyindex + 1)
+			case "h":
+				if len(r.history) > 0 {
+					r.history[r.historyindex].yoffset = r.viewport.YOffset
+				}
+				r.openOverlay(browsing, "History (enter: open)", r.historyEntries())
+				r.overlay.Select(len(r.history) - 1 - r.historyindex)
+				return r, nil
+			case "o":
+				entries := r.visibleNotes()
+				if len(entries) == 0 {
+					r.status = "There are no notes on screen"
+					return r, nil
+				}
+				r.openOverlay(noting, "Notes (enter: open)", entries)
+				return r, nil
+			case "c":
+				if r.books == nil {
+					return r, r.fetchBooks()
+				}
+				r.openBooks()
+				return r, nil
 			case "?":
 				r.mode = help
 			}
-		} else if r.mode == searching {
+		} else if r.mode == searching || r.mode == grepping {
 			switch msg.String() {
 			case "esc":
 				r.mode = read
@@ -255,22 +386,75 @@ func (r *Reader) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "ctrl+c":
 				return r, tea.Quit
 			case "enter":
-				r.viewport.YOffset = 0
+				query := r.searchbuffer
+				grep := r.mode == grepping
+				r.searchbuffer = ""
+				r.mode = read
 
-				content, err := r.Query(r.searchbuffer)
+				if grep {
+					return r, r.fetchGrep(query)
+				}
+				return r, r.fetchQuery(query)
+			case "backspace":
+				if len(r.searchbuffer) > 0 {
+					r.searchbuffer = r.searchbuffer[:len(r.searchbuffer)-1]
+				}
+			default:
+				runes := []rune(msg.String())
+				if len(runes) == 1 && utf8.ValidRune(runes[0]) {
+					r.searchbuffer += string(runes[0])
+				}
+			}
+		} else if r.mode == jumping {
+			switch msg.String() {
+			case "esc":
+				r.mode = read
+				r.searchbuffer = ""
+			case "ctrl+c":
+				return r, tea.Quit
+			case "enter":
+				target := strings.TrimSpace(r.searchbuffer)
+				r.searchbuffer = ""
+				r.mode = read
+
+				top, _ := r.verseAt(r.viewport.YOffset)
+				chapter, number, err := parseJump(target, top.Chapter)
 				if err != nil {
-					e := err.Error()
-					r.viewport.SetContent(style.ErrorStyle.Render(e))
-					r.searchbuffer = ""
-					r.mode = read
+					r.status = err.Error()
 					return r, nil
 				}
-				r.viewport.SetContent(content)
-
-				title := r.searchbuffer
+				if !r.scrollToVerse(chapter, number) {
+					r.status = fmt.Sprintf("%d:%d isn't in this passage", chapter, number)
+				}
+			case "backspace":
+				if len(r.searchbuffer) > 0 {
+					r.searchbuffer = r.searchbuffer[:len(r.searchbuffer)-1]
+				}
+			default:
+				runes := []rune(msg.String())
+				if len(runes) == 1 && utf8.ValidRune(runes[0]) {
+					r.searchbuffer += string(runes[0])
+				}
+			}
+		} else if r.mode == marking {
+			switch msg.String() {
+			case "esc":
+				r.mode = read
+				r.searchbuffer = ""
+			case "ctrl+c":
+				return r, tea.Quit
+			case "enter":
+				label := strings.TrimSpace(r.searchbuffer)
 				r.searchbuffer = ""
 				r.mode = read
-				return r, tea.SetWindowTitle(title)
+
+				verse, _ := r.verseAt(r.viewport.YOffset)
+				mark, err := r.store.AddBookmark(search.Reference{Book: verse.Book, Chapter: verse.Chapter, Verse: verse.Number}, label)
+				if err != nil {
+					r.status = err.Error()
+					return r, nil
+				}
+				r.status = "Bookmarked " + mark.String()
 			case "backspace":
 				if len(r.searchbuffer) > 0 {
 					r.searchbuffer = r.searchbuffer[:len(r.searchbuffer)-1]
@@ -281,6 +465,84 @@ func (r *Reader) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					r.searchbuffer += string(runes[0])
 				}
 			}
+		} else if r.mode == bookmarking {
+			if msg.String() == "ctrl+c" {
+				return r, tea.Quit
+			}
+
+			if msg.String() == "x" && r.overlay.FilterState() != list.Filtering {
+				if e, ok := r.overlay.SelectedItem().(entry); ok {
+					if err := r.store.RemoveBookmark(e.id); err != nil {
+						r.status = err.Error()
+						return r, nil
+					}
+					// The index of the selected item is into the filtered
+					// items, so the bookmark is looked for among all of them
+					for i, item := range r.overlay.Items() {
+						if item.(entry).id == e.id {
+							r.overlay.RemoveItem(i)
+							break
+						}
+					}
+				}
+				return r, nil
+			}
+
+			e, cmd := r.updateOverlay(msg)
+			if e != nil {
+				return r, r.fetchReference(e.reference)
+			}
+			return r, cmd
+		} else if r.mode == browsing {
+			if msg.String() == "ctrl+c" {
+				return r, tea.Quit
+			}
+
+			e, cmd := r.updateOverlay(msg)
+			if e != nil {
+				return r, r.fetchVisit(e.id)
+			}
+			return r, cmd
+		} else if r.mode == noting {
+			if msg.String() == "ctrl+c" {
+				return r, tea.Quit
+			}
+
+			e, cmd := r.updateOverlay(msg)
+			if e != nil {
+				if e.note.Kind == model.CrossReference {
+					return r, r.fetchQuery(e.note.References)
+				}
+				r.note = *e
+				r.mode = footnote
+			}
+			return r, cmd
+		} else if r.mode == picking {
+			if msg.String() == "ctrl+c" {
+				return r, tea.Quit
+			}
+
+			e, cmd := r.updateOverlay(msg)
+			if e != nil {
+				if e.header {
+					r.mode = picking
+				} else {
+					r.openChapters(e.book)
+				}
+			}
+			return r, cmd
+		} else if r.mode == chapters {
+			if msg.String() == "ctrl+c" {
+				return r, tea.Quit
+			}
+			return r, r.updateChapters(msg)
+		} else if r.mode == footnote {
+			switch msg.String() {
+			case "esc", "q", "enter":
+				r.mode = read
+			case "ctrl+c":
+				return r, tea.Quit
+			}
 		} else if r.mode == help {
 			switch msg.String() {
 			case "esc", "q":
@@ -291,45 +553,155 @@ func (r *Reader) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			panic("Invalid mode")
 		}
+	case passageMsg:
+		if msg.id != r.fetchid {
+			return r, nil
+		}
+		r.cancelfetch()
+		r.cancelfetch = nil
+		r.loading = ""
+
+		if msg.books != nil {
+			r.books = msg.books
+		}
+
+		if msg.picker {
+			if msg.err != nil {
+				r.status = msg.err.Error()
+				return r, nil
+			}
+			r.openBooks()
+			return r, nil
+		}
+
+		if msg.err != nil {
+			r.viewport.YOffset = 0
+			r.viewport.SetContent(style.ErrorStyle.Render(msg.err.Error()))
+			return r, nil
+		}
+
+		if msg.revisit {
+			r.historyindex = msg.visit
+		} else {
+			r.remember(visit{query: msg.query, hits: msg.hits})
+		}
+
+		r.query = msg.query
+		r.verses = msg.verses
+		r.parallel = msg.parallel
+		r.hits = msg.hits
+
+		r.viewport.YOffset = 0
+		r.viewport.SetContent(r.RenderVerses())
+		if msg.revisit {
+			r.viewport.SetYOffset(msg.yoffset)
+		} else {
+			r.target = msg.target
+			r.scrollToTarget()
+		}
+		return r, tea.SetWindowTitle(r.query)
+	case spinner.TickMsg:
+		if r.loading == "" {
+			return r, nil
+		}
+		var cmd tea.Cmd
+		r.spinner, cmd = r.spinner.Update(msg)
+		return r, cmd
 	case tea.WindowSizeMsg:
 		if !r.ready {
 			r.ready = true
 			r.viewport = viewport.New(msg.Width, msg.Height-2)
 			r.viewport.Style = r.viewport.Style.Padding(0, r.padding)
-
-			content, err := r.Query(r.query)
-			if err != nil {
-				e := err.Error()
-				r.viewport.SetContent(style.ErrorStyle.Render(e))
-				return r, nil
-			}
-
-			r.viewport.SetContent(content)
+			r.viewport.SetContent(r.RenderVerses())
+			r.scrollToTarget()
 		} else {
 			r.viewport.YOffset = 0
 			r.viewport.Width = msg.Width
 			r.viewport.Height = msg.Height - 2
 			r.viewport.SetContent(r.RenderVerses())
+			r.overlay.SetSize(r.viewport.Width-(2*r.padding), r.viewport.Height)
 		}
 	}
 
 	var cmd tea.Cmd
 	if r.mode == read {
 		r.viewport, cmd = r.viewport.Update(msg)
+	} else if r.mode == bookmarking || r.mode == browsing || r.mode == noting || r.mode == picking {
+		r.overlay, cmd = r.overlay.Update(msg)
 	}
 	return r, cmd
 }
 
-const helptext = "q/esc: quit\n\ng/G: top/bottom\n\np/n: prev/next chapter\n\n+/-: increase/decrease padding\n\nw: toggle wrap\n\n/: search\n\n?: help\n\n"
+// visibleNotes lists the notes of every verse of the primary translation that
+// is at least partly on screen.
+func (r *Reader) visibleNotes() []entry {
+	top := r.viewport.YOffset
+	bottom := top + r.viewport.Height
+
+	var entries []entry
+	for i, verse := range r.verses {
+		if i >= len(r.offsets) || r.offsets[i] >= bottom {
+			break
+		}
+		if i+1 < len(r.offsets) && r.offsets[i+1] <= top {
+			continue
+		}
+
+		for _, note := range verse.Notes {
+			marker := "[" + note.Marker + "]"
+			if note.Kind == model.CrossReference {
+				marker = "(" + note.Marker + ")"
+			}
+			entries = append(entries, entry{
+				title:       fmt.Sprintf("%s %s %d:%d", marker, verse.Book, verse.Chapter, verse.Number),
+				description: note.Text,
+				note:        note,
+			})
+		}
+	}
+	return entries
+}
+
+const helptext = "q/esc: quit (esc cancels loading)\n\ng/G: top/bottom\n\np/n: prev/next chapter\n\n+/-: increase/decrease padding\n\nw: toggle wrap\n\n/: search\n\ns: search words\n\n:: jump to verse\n\nm: bookmark top verse\n\n': bookmarks\n\nH/L: back/forward\n\nh: history\n\no: notes on screen\n\nc: pick a book and chapter\n\n?: help\n\n"
 
 func (r *Reader) Header() string {
-	return style.HeaderStyle.Width(r.viewport.Width-(2*r.padding)).Margin(0, r.padding).Render(r.searcher.Translation())
+	width := r.viewport.Width - (2 * r.padding)
+	if len(r.searchers) == 1 || r.hits {
+		return style.HeaderStyle.Width(width).Margin(0, r.padding).Render(r.searchers[0].Translation())
+	}
+
+	colwidth := ColumnWidth(width, len(r.searchers))
+	names := make([]string, len(r.searchers))
+	for i, searcher := range r.searchers {
+		s := style.HeaderStyle.Width(colwidth)
+		if i > 0 {
+			s = s.MarginLeft(columngap)
+		}
+		names[i] = s.Render(searcher.Translation())
+	}
+	return lipgloss.NewStyle().Margin(0, r.padding).Render(lipgloss.JoinHorizontal(lipgloss.Top, names...))
 }
 
 func (r *Reader) Footer() string {
 	if r.mode == searching {
 		return style.SearchStyle.Padding(0, r.padding).Render("/" + r.searchbuffer)
 	}
+	if r.mode == grepping {
+		return style.SearchStyle.Padding(0, r.padding).Render("s/" + r.searchbuffer)
+	}
+	if r.mode == jumping {
+		return style.SearchStyle.Padding(0, r.padding).Render(":" + r.searchbuffer)
+	}
+	if r.mode == marking {
+		verse, _ := r.verseAt(r.viewport.YOffset)
+		return style.SearchStyle.Padding(0, r.padding).Render(fmt.Sprintf("Bookmark %s %d:%d label: %s", verse.Book, verse.Chapter, verse.Number, r.searchbuffer))
+	}
+	if r.loading != "" {
+		return style.SearchStyle.Padding(0, r.padding).Render(fmt.Sprintf("%s Loading %s... (esc to cancel)", r.spinner.View(), r.loading))
+	}
+	if r.status != "" {
+		return style.SearchStyle.Padding(0, r.padding).Render(r.status)
+	}
 	return ""
 }
 
@@ -358,6 +730,36 @@ func (r *Reader) View() string {
 			r.Footer(),
 		)
 	}
+	if r.mode == footnote {
+		width := min(r.viewport.Width-(2*r.padding), 60)
+		text := style.NumberStyle.Render(r.note.title) + "\n\n" + ResizeString(r.note.description, width, "") + "\n\n" + style.NoteStyle.Render("esc: close")
+
+		hpad := (r.viewport.Width - lipgloss.Width(text)) / 2
+		vpad := (r.viewport.Height - lipgloss.Height(text)) / 2
+
+		return fmt.Sprintf(
+			"%s\n%s\n%s",
+			r.Header(),
+			lipgloss.NewStyle().Padding(vpad, max(0, hpad)).Height(r.viewport.Height).Render(text),
+			r.Footer(),
+		)
+	}
+	if r.mode == chapters {
+		return fmt.Sprintf(
+			"%s\n%s\n%s",
+			r.Header(),
+			lipgloss.NewStyle().Padding(0, r.padding).Height(r.viewport.Height).Render(r.ChaptersView()),
+			r.Footer(),
+		)
+	}
+	if r.mode == bookmarking || r.mode == browsing || r.mode == noting || r.mode == picking {
+		return fmt.Sprintf(
+			"%s\n%s\n%s",
+			r.Header(),
+			lipgloss.NewStyle().Padding(0, r.padding).Height(r.viewport.Height).Render(r.overlay.View()),
+			r.Footer(),
+		)
+	}
 	return fmt.Sprintf(
 		"%s\n%s\n%s",
 		r.Header(),
@@ -0,0 +1,138 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nilptrderef/bgate/reader/model"
+	"github.com/nilptrderef/bgate/search"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakesearcher has no passages, for tests that only need a reader to exist.
+type fakesearcher struct{}
+
+func (fakesearcher) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	return nil, nil
+}
+
+func (fakesearcher) Booklist(ctx context.Context) ([]model.Book, error) {
+	return nil, nil
+}
+
+func (fakesearcher) Translation() string {
+	return "FAKE"
+}
+
+func TestResizeBeforeOverlay(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "John 3")
+	r.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	r.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	if r.viewport.Width != 100 || r.viewport.Height != 28 {
+		t.Fatalf("Expected the viewport to be resized, got %dx%d", r.viewport.Width, r.viewport.Height)
+	}
+}
+
+func TestHistoryOverlaySelectsCurrent(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "John 3")
+	r.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+	for chapter := 1; chapter <= 30; chapter++ {
+		r.history = append(r.history, visit{query: fmt.Sprintf("John %d", chapter)})
+	}
+	r.historyindex = 5
+
+	r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
+	if r.mode != browsing {
+		t.Fatalf("Expected the history to be open")
+	}
+	if e, ok := r.overlay.SelectedItem().(entry); !ok || e.id != r.historyindex {
+		t.Fatalf("Expected the current visit to be selected, got %+v", r.overlay.SelectedItem())
+	}
+}
+
+func TestParseJump(t *testing.T) {
+	tests := []struct {
+		target  string
+		chapter int
+		verse   int
+		err     bool
+	}{
+		{target: "16", chapter: 3, verse: 16},
+		{target: "4:2", chapter: 4, verse: 2},
+		{target: " 3 : 16 ", chapter: 3, verse: 16},
+		{target: "sixteen", err: true},
+		{target: "3:", err: true},
+		{target: ":16", err: true},
+		{target: "", err: true},
+	}
+
+	for _, test := range tests {
+		chapter, verse, err := parseJump(test.target, 3)
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %d:%d", test.target, chapter, verse)
+			}
+			continue
+		}
+		if err != nil || chapter != test.chapter || verse != test.verse {
+			t.Errorf("Expected %q to be %d:%d, got %d:%d (%v)", test.target, test.chapter, test.verse, chapter, verse, err)
+		}
+	}
+}
+
+func TestJumpToVerse(t *testing.T) {
+	r := NewReader([]search.Searcher{fakesearcher{}}, "John 3")
+	r.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	for number := 1; number <= 36; number++ {
+		r.verses = append(r.verses, model.Verse{
+			Book: "John", Chapter: 3, Number: number, Part: 1,
+			Text: "For God so loved the world that he gave his one and only Son",
+		})
+	}
+	r.viewport.SetContent(r.RenderVerses())
+
+	for _, key := range []string{":", "1", "6"} {
+		r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	r.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if r.mode != read || r.viewport.YOffset != r.offsets[15] || r.offsets[15] == 0 {
+		t.Fatalf("Expected to be at 3:16 on line %d, got line %d (%s)", r.offsets[15], r.viewport.YOffset, r.status)
+	}
+
+	r.hits = true
+	r.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	if r.mode != read {
+		t.Fatalf("Expected search results not to be jumped in")
+	}
+}
+
+func TestOffsetBeforeResize(t *testing.T) {
+	var verses []model.Verse
+	for number := 1; number <= 36; number++ {
+		verses = append(verses, model.Verse{
+			Book: "John", Chapter: 3, Number: number, Part: 1,
+			Text: "For God so loved the world that he gave his one and only Son",
+		})
+	}
+
+	r := NewReader([]search.Searcher{fakesearcher{}}, "John 3")
+	r.SetYOffset(20)
+	r.cancelfetch = func() {}
+	r.Update(passageMsg{id: r.fetchid, query: "John 3", verses: verses})
+	r.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	if r.viewport.YOffset != 20 {
+		t.Fatalf("Expected the saved offset to be kept until the viewport is ready, got %d", r.viewport.YOffset)
+	}
+
+	r = NewReader([]search.Searcher{fakesearcher{}}, "John 3:16")
+	r.cancelfetch = func() {}
+	r.Update(passageMsg{id: r.fetchid, query: "John 3:16", verses: verses, target: search.Reference{Chapter: 3, Verse: 16}})
+	r.Update(tea.WindowSizeMsg{Width: 40, Height: 8})
+	if r.viewport.YOffset != r.offsets[15] || r.offsets[15] == 0 {
+		t.Fatalf("Expected to open at 3:16 on line %d, got %d", r.offsets[15], r.viewport.YOffset)
+	}
+}
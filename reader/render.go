@@ -0,0 +1,203 @@
+package reader
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// RenderVerses lays out a passage to fit within width, starting a new
+// paragraph for each title and chapter. Unless wrap is set, every verse also
+// starts on a new line with its continuation lines indented. Paragraphs start
+// on a new line, and each line of poetry is a line of its own, indented by
+// its level with a hanging indent for the lines it continues onto. The words
+// of Jesus are only shown in red if redletter is set.
+func RenderVerses(verses []model.Verse, width int, wrap bool, redletter bool) string {
+	content, _ := layoutVerses(redLetters(verses, redletter), width, wrap)
+	return content
+}
+
+// redLetters returns verses as they are if redletter is set, and otherwise
+// without the spans marking the words of Jesus.
+func redLetters(verses []model.Verse, redletter bool) []model.Verse {
+	if redletter {
+		return verses
+	}
+
+	plain := make([]model.Verse, len(verses))
+	for i, verse := range verses {
+		plain[i] = verse.WithoutSpans(model.WordsOfJesus)
+	}
+	return plain
+}
+
+// layoutVerses does the work of RenderVerses, also returning the line each
+// verse starts on, including any title or chapter heading above it.
+func layoutVerses(verses []model.Verse, width int, wrap bool) (string, []int) {
+	type start struct{ line, word int }
+	starts := make([]start, len(verses))
+
+	indentation := "    "
+	if wrap {
+		indentation = ""
+	}
+	indentations := map[int]string{}
+
+	// Words are split on spaces, so the number of spaces since the last
+	// newline is the index of the next word. Both are counted as the text is
+	// written rather than by going back over it for every verse.
+	var writer strings.Builder
+	var line, word int
+	write := func(text string) {
+		writer.WriteString(text)
+		if last := strings.LastIndex(text, "\n"); last != -1 {
+			line += strings.Count(text, "\n")
+			word = strings.Count(text[last+1:], " ")
+		} else {
+			word += strings.Count(text, " ")
+		}
+	}
+
+	for index, verse := range verses {
+		title := verse.HasTitle()
+		chapter := verse.Number == 1 && verse.Part == 1
+
+		if index > 0 && wrap && (title || chapter) {
+			write("\n")
+		}
+
+		// Prose flows on from the verse before unless it's a new paragraph
+		// or comes after poetry
+		if index > 0 && wrap && !title && !chapter && (verse.Paragraph || verse.Poetry || verses[index-1].Poetry) {
+			write("\n")
+		}
+
+		starts[index] = start{line, word}
+
+		if title {
+			write(verse.TitleString() + "\n")
+		}
+
+		if chapter {
+			write(verse.ChapterString() + "\n")
+		}
+
+		if verse.Poetry {
+			lead := strings.Repeat("  ", verse.Indent)
+			write(lead)
+			indentations[line] = lead + "    "
+		}
+
+		if verse.Part == 1 {
+			write(verse.NumberString())
+		}
+
+		write(verse.MarkedText() + " ")
+
+		if !wrap {
+			write("\n")
+		}
+	}
+
+	lines := strings.Split(writer.String(), "\n")
+	lineindentations := make([]string, len(lines))
+	for i := range lines {
+		lineindentations[i] = indentation
+		if lead, ok := indentations[i]; ok {
+			lineindentations[i] = lead
+		}
+	}
+	content, positions := resizeLines(lines, width, lineindentations)
+
+	offsets := make([]int, len(verses))
+	for i, s := range starts {
+		offsets[i] = positions[s.line][s.word]
+	}
+	return content, offsets
+}
+
+// columngap is the number of spaces between the columns of a parallel view.
+const columngap = 4
+
+// ColumnWidth is the width of each of n columns sharing width.
+func ColumnWidth(width int, n int) int {
+	if n <= 1 {
+		return width
+	}
+	return max(1, (width-columngap*(n-1))/n)
+}
+
+type versekey struct {
+	book    string
+	chapter int
+	number  int
+}
+
+// RenderParallel lays out the same passage from several translations side by
+// side, one column each. Every verse starts on the same row across all of the
+// columns so that the renderings can be compared, which means verses are never
+// wrapped together.
+func RenderParallel(passages [][]model.Verse, width int, redletter bool) string {
+	plain := make([][]model.Verse, len(passages))
+	for i, verses := range passages {
+		plain[i] = redLetters(verses, redletter)
+	}
+	content, _ := layoutParallel(plain, width)
+	return content
+}
+
+// layoutParallel does the work of RenderParallel, also returning the line each
+// verse of the first passage starts on.
+func layoutParallel(passages [][]model.Verse, width int) (string, []int) {
+	if len(passages) == 1 {
+		return layoutVerses(passages[0], width, false)
+	}
+
+	// Some translations include verses that others leave out, so the rows are
+	// the union of every passage's verses in the order they first appear.
+	var keys []versekey
+	cells := map[versekey][][]model.Verse{}
+	for column, verses := range passages {
+		at := 0
+		for _, verse := range verses {
+			key := versekey{verse.Book, verse.Chapter, verse.Number}
+			if _, ok := cells[key]; !ok {
+				cells[key] = make([][]model.Verse, len(passages))
+				keys = slices.Insert(keys, at, key)
+			}
+			at = slices.Index(keys, key) + 1
+			cells[key][column] = append(cells[key][column], verse)
+		}
+	}
+
+	colwidth := ColumnWidth(width, len(passages))
+	cellstyle := lipgloss.NewStyle().Width(colwidth)
+	gapstyle := cellstyle.Copy().MarginLeft(columngap)
+
+	rowlines := map[versekey]int{}
+	rows := make([]string, 0, len(keys))
+	var line int
+	for _, key := range keys {
+		row := make([]string, len(passages))
+		for column, verses := range cells[key] {
+			s := cellstyle
+			if column > 0 {
+				s = gapstyle
+			}
+			content, _ := layoutVerses(verses, colwidth, false)
+			row[column] = s.Render(content)
+		}
+		rendered := lipgloss.JoinHorizontal(lipgloss.Top, row...)
+		rows = append(rows, rendered)
+		rowlines[key] = line
+		line += lipgloss.Height(rendered)
+	}
+
+	offsets := make([]int, len(passages[0]))
+	for i, verse := range passages[0] {
+		offsets[i] = rowlines[versekey{verse.Book, verse.Chapter, verse.Number}]
+	}
+	return strings.Join(rows, "\n"), offsets
+}
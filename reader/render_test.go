@@ -0,0 +1,111 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+func TestLayoutVersesOffsets(t *testing.T) {
+	title := "Title"
+	verses := []model.Verse{
+		{Book: "John", Chapter: 3, Number: 1, Part: 1, Text: "one two three four five six", Title: &title},
+		{Book: "John", Chapter: 3, Number: 2, Part: 1, Text: "seven eight"},
+		{Book: "John", Chapter: 3, Number: 3, Part: 1, Text: "nine ten eleven twelve"},
+	}
+
+	tests := []struct {
+		wrap    bool
+		offsets []int
+	}{
+		// Title, chapter, then verse one spread over two lines
+		{false, []int{0, 4, 5}},
+		{true, []int{0, 3, 4}},
+	}
+
+	for _, test := range tests {
+		content, offsets := layoutVerses(verses, 20, test.wrap)
+		if len(offsets) != len(test.offsets) {
+			t.Fatalf("Expected %d offsets, got %d", len(test.offsets), len(offsets))
+		}
+		for i := range offsets {
+			if offsets[i] != test.offsets[i] {
+				t.Fatalf("Unexpected offsets with wrap %v: %v\n%s", test.wrap, offsets, content)
+			}
+		}
+
+		lines := strings.Split(content, "\n")
+		for i, verse := range verses[1:] {
+			if !strings.Contains(lines[offsets[i+1]], verse.Text[:4]) {
+				t.Fatalf("Verse %d doesn't start on line %d with wrap %v:\n%s", verse.Number, offsets[i+1], test.wrap, content)
+			}
+		}
+	}
+}
+
+func TestLayoutVersesPoetry(t *testing.T) {
+	verses := []model.Verse{
+		{Book: "Psalm", Chapter: 23, Number: 2, Part: 1, Text: "He makes me lie down", Paragraph: true, Poetry: true},
+		{Book: "Psalm", Chapter: 23, Number: 2, Part: 2, Text: "in green pastures beside still waters", Poetry: true, Indent: 1},
+		{Book: "Psalm", Chapter: 23, Number: 3, Part: 1, Text: "He restores", Paragraph: true},
+	}
+
+	for _, wrap := range []bool{false, true} {
+		content, offsets := layoutVerses(verses, 20, wrap)
+		lines := strings.Split(content, "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+
+		expected := []string{
+			"2 He makes me lie",
+			"    down",
+			"  in green pastures",
+			"      beside still",
+			"      waters",
+			"3 He restores",
+		}
+		if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("Unexpected layout with wrap %v:\n%s", wrap, strings.Join(lines, "\n"))
+		}
+		if offsets[1] != 2 || offsets[2] != 5 {
+			t.Fatalf("Unexpected offsets with wrap %v: %v", wrap, offsets)
+		}
+	}
+}
+
+func TestLayoutVersesMarkup(t *testing.T) {
+	verses := []model.Verse{{
+		Book: "Psalm", Chapter: 23, Number: 1, Part: 1,
+		Text:  "The Lord is my shepherd",
+		Notes: []model.Note{{Kind: model.Footnote, Marker: "a", Offset: 8}},
+		Spans: []model.Span{{Kind: model.SmallCaps, Offset: 4, Length: 4}},
+	}}
+
+	content, _ := layoutVerses(verses, 80, false)
+	if !strings.Contains(content, "The LORD[a] is my shepherd") {
+		t.Fatalf("Expected small caps and the footnote marker:\n%s", content)
+	}
+}
+
+func TestWriteVersesRedLetter(t *testing.T) {
+	verses := []model.Verse{{
+		Book: "John", Chapter: 3, Number: 3, Part: 1,
+		Text:  "Jesus answered, Very truly I tell you",
+		Spans: []model.Span{{Kind: model.WordsOfJesus, Offset: 16, Length: 21}},
+	}}
+
+	for _, redletter := range []bool{true, false} {
+		var writer strings.Builder
+		if err := WriteVerses(&writer, verses, FormatHTML, 0, false, redletter); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(writer.String(), `class="woj"`) != redletter {
+			t.Fatalf("Expected the words of Jesus to be marked only with red letters (%v):\n%s", redletter, writer.String())
+		}
+	}
+	if len(verses[0].Spans) != 1 {
+		t.Fatalf("Expected the passage to be left as it was")
+	}
+}
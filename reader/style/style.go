@@ -1,6 +1,10 @@
 package style
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 var TitleStyle = lipgloss.NewStyle().
 	Bold(true).
@@ -22,3 +26,13 @@ var SearchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB347")).Bold
 var HelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB347")).Align(lipgloss.Center)
 
 var ErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6666"))
+
+var NoteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#8D99AE"))
+
+var WordsOfJesusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#E63946"))
+
+var SmallCapsStyle = lipgloss.NewStyle().Transform(strings.ToUpper)
+
+var ItalicStyle = lipgloss.NewStyle().Italic(true)
+
+var SelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB347")).Bold(true).Reverse(true)
@@ -7,17 +7,38 @@ import (
 )
 
 func ResizeString(s string, width int, indentation string) string {
+	resized, _ := resize(s, width, indentation)
+	return resized
+}
+
+// resize does the work of ResizeString, also returning the output line that
+// each space separated word of each input line ended up on.
+func resize(s string, width int, indentation string) (string, [][]int) {
 	lines := strings.Split(s, "\n")
+	indentations := make([]string, len(lines))
+	for i := range indentations {
+		indentations[i] = indentation
+	}
+	return resizeLines(lines, width, indentations)
+}
+
+// resizeLines wraps each line to width, indenting the lines it continues onto
+// by its own indentation.
+func resizeLines(lines []string, width int, indentations []string) (string, [][]int) {
+	positions := make([][]int, len(lines))
 
 	var writer strings.Builder
+	var outline int
 
-	for _, line := range lines {
+	for i, line := range lines {
+		indentation := indentations[i]
 		words := strings.Split(line, " ")
+		positions[i] = make([]int, len(words))
 		var chunks []string
 		var current []string
 		var ccount int
 
-		for _, word := range words {
+		for j, word := range words {
 			var wsize, _ = lipgloss.Size(word)
 			var size = width
 			if len(chunks) > 0 {
@@ -36,6 +57,7 @@ func ResizeString(s string, width int, indentation string) string {
 
 			ccount += wsize + 1
 			current = append(current, word)
+			positions[i][j] = outline + len(chunks)
 		}
 
 		if len(chunks) > 0 {
@@ -43,7 +65,20 @@ func ResizeString(s string, width int, indentation string) string {
 		}
 		chunks = append(chunks, strings.Join(current, " "))
 		writer.WriteString(strings.Join(chunks, "\n") + "\n")
+		outline += len(chunks)
+	}
+
+	// Account for any blank lines trimmed from the start, keeping the
+	// indentation of the first line that isn't blank
+	out := strings.TrimRight(writer.String(), " \t\n")
+	blank := out[:len(out)-len(strings.TrimLeft(out, " \t\n"))]
+	blank = blank[:strings.LastIndex(blank, "\n")+1]
+	trimmed := strings.Count(blank, "\n")
+	for i := range positions {
+		for j := range positions[i] {
+			positions[i][j] = max(0, positions[i][j]-trimmed)
+		}
 	}
 
-	return strings.TrimSpace(writer.String())
+	return out[len(blank):], positions
 }
@@ -1,5 +1,7 @@
 package search
 
+import "strings"
+
 var abbreviations = map[string]string{
 	"ge":      "Genesis",
 	"gn":      "Genesis",
@@ -432,3 +434,29 @@ var abbreviations = map[string]string{
 	"re":            "Revelation",
 	"therevelation": "Revelation",
 }
+
+// newtestament holds the canonical names of the books of the New Testament,
+// as abbreviations resolves them.
+var newtestament = map[string]bool{
+	"Matthew": true, "Mark": true, "Luke": true, "John": true, "Acts": true,
+	"Romans": true, "1 Corinthians": true, "2 Corinthians": true, "Galatians": true,
+	"Ephesians": true, "Philippians": true, "Colossians": true,
+	"1 Thessalonians": true, "2 Thessalonians": true, "1 Timothy": true,
+	"2 Timothy": true, "Titus": true, "Philemon": true, "Hebrews": true,
+	"James": true, "1 Peter": true, "2 Peter": true, "1 John": true,
+	"2 John": true, "3 John": true, "Jude": true, "Revelation": true,
+}
+
+// Testament returns the testament a book belongs to, looking its name up the
+// same way as in a query. Books in neither, such as those of the Apocrypha,
+// return an empty string.
+func Testament(book string) string {
+	canonical, ok := abbreviations[strings.ToLower(strings.ReplaceAll(book, " ", ""))]
+	if !ok {
+		return ""
+	}
+	if newtestament[canonical] {
+		return "New Testament"
+	}
+	return "Old Testament"
+}
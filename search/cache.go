@@ -0,0 +1,327 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// DefaultCacheTTL is how long cached passages are used before they're fetched
+// again, unless configured otherwise.
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// DefaultCacheSize is how many bytes the cache may grow to before the least
+// recently used passages are dropped, unless configured otherwise.
+const DefaultCacheSize = 50 << 20
+
+// cacheFormat is the version of the shape of cached values. It must be bumped
+// whenever model.Verse gains or changes a field, so that entries cached before
+// are fetched again rather than served without it. Entries in an older format
+// are only used when fetching fails. Format 2 added notes, layout and spans.
+const cacheFormat = 2
+
+// booklistKey is the key the booklist of a translation is cached under, which
+// can't be mistaken for a reference.
+const booklistKey = ":booklist"
+
+// Cache keeps passages and booklists fetched from BibleGateway on disk, so
+// that recently read passages can be read again offline.
+type Cache struct {
+	db      *sqlx.DB
+	ttl     time.Duration
+	maxsize int64
+}
+
+// CacheStats describes what's in the cache.
+type CacheStats struct {
+	Path         string
+	Entries      int
+	Size         int64
+	Translations map[string]int
+	Oldest       time.Time
+	Newest       time.Time
+}
+
+// OpenCache opens the cache in the bgate directory. Entries older than ttl
+// are fetched again, and once the cache is bigger than maxsize bytes the
+// least recently used entries are dropped.
+func OpenCache(ttl time.Duration, maxsize int64) (*Cache, error) {
+	cachepath, err := cachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(path.Dir(cachepath), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlx.Open("sqlite3", cachepath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS entries (
+		translation TEXT,
+		key TEXT,
+		value TEXT,
+		size INTEGER,
+		fetched DATETIME,
+		used DATETIME,
+		version INTEGER NOT NULL DEFAULT 1,
+		PRIMARY KEY (translation, key)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Caches from before entries were versioned hold format 1
+	var versioned int
+	err = db.Get(&versioned, "SELECT count(*) FROM pragma_table_info('entries') WHERE name = 'version'")
+	if err == nil && versioned == 0 {
+		_, err = db.Exec("ALTER TABLE entries ADD COLUMN version INTEGER NOT NULL DEFAULT 1")
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Cache{db, ttl, maxsize}, nil
+}
+
+func cachePath() (string, error) {
+	bgatepath, err := datadir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(bgatepath, "cache.db"), nil
+}
+
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// cacheKey normalizes a query so that different ways of writing the same
+// reference share an entry. Queries the parser doesn't understand are only
+// normalized for case and spacing.
+func cacheKey(query string) string {
+	ranges, err := ParseReference(query)
+	if err != nil {
+		return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	}
+
+	keys := make([]string, len(ranges))
+	for i, r := range ranges {
+		keys[i] = r.String()
+	}
+	return strings.Join(keys, "; ")
+}
+
+// get reads an entry into value, reporting whether it was found and whether
+// it's still fresh. Entries in an older format are never fresh.
+func (c *Cache) get(ctx context.Context, translation string, key string, value any) (found bool, fresh bool, err error) {
+	var entry struct {
+		Value   string    `db:"value"`
+		Fetched time.Time `db:"fetched"`
+		Version int       `db:"version"`
+	}
+	err = c.db.GetContext(ctx, &entry, "SELECT value, fetched, version FROM entries WHERE translation = ? AND key = ?", translation, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	err = json.Unmarshal([]byte(entry.Value), value)
+	if err != nil {
+		return false, false, err
+	}
+
+	_, err = c.db.ExecContext(ctx, "UPDATE entries SET used = CURRENT_TIMESTAMP WHERE translation = ? AND key = ?", translation, key)
+	if err != nil {
+		return false, false, err
+	}
+
+	fresh = entry.Version == cacheFormat && (c.ttl <= 0 || time.Since(entry.Fetched) < c.ttl)
+	return true, fresh, nil
+}
+
+// put stores an entry, then drops the least recently used entries until the
+// cache fits in its size.
+func (c *Cache) put(ctx context.Context, translation string, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO entries (translation, key, value, size, fetched, used, version)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, ?)`,
+		translation, key, string(data), len(data), cacheFormat)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, `
+		DELETE FROM entries WHERE rowid IN (
+			SELECT rowid FROM (
+				SELECT rowid, sum(size) OVER (ORDER BY used DESC, rowid DESC) AS total FROM entries
+			) WHERE total > ?
+		)`, c.maxsize)
+	return err
+}
+
+// Clear removes every entry, or only those of the given translations.
+func (c *Cache) Clear(translations ...string) error {
+	if len(translations) == 0 {
+		_, err := c.db.Exec("DELETE FROM entries")
+		if err != nil {
+			return err
+		}
+	} else {
+		query, args, err := sqlx.In("DELETE FROM entries WHERE translation IN (?)", translations)
+		if err != nil {
+			return err
+		}
+		_, err = c.db.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := c.db.Exec("VACUUM")
+	return err
+}
+
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Translations: map[string]int{}}
+
+	cachepath, err := cachePath()
+	if err != nil {
+		return stats, err
+	}
+	stats.Path = cachepath
+
+	var entries []struct {
+		Translation string    `db:"translation"`
+		Size        int64     `db:"size"`
+		Fetched     time.Time `db:"fetched"`
+	}
+	err = c.db.Select(&entries, "SELECT translation, size, fetched FROM entries")
+	if err != nil {
+		return stats, err
+	}
+
+	for _, entry := range entries {
+		stats.Entries++
+		stats.Size += entry.Size
+		stats.Translations[entry.Translation]++
+		if stats.Oldest.IsZero() || entry.Fetched.Before(stats.Oldest) {
+			stats.Oldest = entry.Fetched
+		}
+		if entry.Fetched.After(stats.Newest) {
+			stats.Newest = entry.Fetched
+		}
+	}
+
+	return stats, nil
+}
+
+// Cached is a Searcher that answers from a cache when it can, and otherwise
+// asks source and caches its answer. Entries past their time to live are
+// fetched again, but are still used if source fails, such as when offline.
+type Cached struct {
+	source Searcher
+	cache  *Cache
+}
+
+func NewCached(source Searcher, cache *Cache) *Cached {
+	return &Cached{source, cache}
+}
+
+func (c *Cached) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	return cached(ctx, c, cacheKey(query), func() ([]model.Verse, error) {
+		return c.source.Query(ctx, query)
+	})
+}
+
+func (c *Cached) Booklist(ctx context.Context) ([]model.Book, error) {
+	return cached(ctx, c, booklistKey, func() ([]model.Book, error) {
+		return c.source.Booklist(ctx)
+	})
+}
+
+// cached looks up key, fetching and caching it if it isn't cached or has gone
+// stale. Empty results aren't cached, and problems with the cache itself are
+// ignored since the source can still answer.
+func cached[T any](ctx context.Context, c *Cached, key string, fetch func() ([]T, error)) ([]T, error) {
+	translation := c.source.Translation()
+
+	var value []T
+	found, fresh, err := c.cache.get(ctx, translation, key, &value)
+	if err == nil && found && fresh {
+		return value, nil
+	}
+
+	fetched, ferr := fetch()
+	if ferr != nil {
+		if err == nil && found && ctx.Err() == nil {
+			return value, nil
+		}
+		return nil, ferr
+	}
+
+	if len(fetched) > 0 {
+		c.cache.put(ctx, translation, key, fetched)
+	}
+	return fetched, nil
+}
+
+func (c *Cached) Translation() string {
+	return c.source.Translation()
+}
+
+// CacheSearcher is a Searcher that only answers from the cache, finding
+// nothing for passages that aren't cached or have gone stale.
+type CacheSearcher struct {
+	cache       *Cache
+	translation string
+}
+
+// Searcher searches the passages of translation in the cache.
+func (c *Cache) Searcher(translation string) *CacheSearcher {
+	return &CacheSearcher{c, translation}
+}
+
+func (c *CacheSearcher) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	var verses []model.Verse
+	found, fresh, err := c.cache.get(ctx, c.translation, cacheKey(query), &verses)
+	if err != nil || !found || !fresh {
+		return nil, err
+	}
+	return verses, nil
+}
+
+func (c *CacheSearcher) Booklist(ctx context.Context) ([]model.Book, error) {
+	var books []model.Book
+	found, fresh, err := c.cache.get(ctx, c.translation, booklistKey, &books)
+	if err != nil || !found || !fresh {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (c *CacheSearcher) Translation() string {
+	return c.translation
+}
@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// countingsource counts the queries that reach it, failing them all once
+// offline is set.
+type countingsource struct {
+	fakesource
+	queries int
+	offline bool
+}
+
+func (c *countingsource) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	c.queries++
+	if c.offline {
+		return nil, errors.New("offline")
+	}
+	return c.fakesource.Query(ctx, query)
+}
+
+func TestCached(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+
+	cache, err := OpenCache(time.Hour, DefaultCacheSize)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	source := &countingsource{}
+	cached := NewCached(source, cache)
+
+	// Different ways of writing the same reference share an entry
+	for _, query := range []string{"Genesis 1", "gen 1", "Genesis  1"} {
+		verses, err := cached.Query(ctx, query)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(verses) != 3 {
+			t.Fatalf("Expected 3 verses for %s, got %d", query, len(verses))
+		}
+	}
+	if source.queries != 1 {
+		t.Fatalf("Expected 1 query to reach the source, got %d", source.queries)
+	}
+
+	// Stale entries are fetched again, but still used when that fails
+	cache.ttl = time.Nanosecond
+	source.offline = true
+	verses, err := cached.Query(ctx, "Genesis 1")
+	if err != nil || len(verses) != 3 || source.queries != 2 {
+		t.Fatalf("Expected the stale entry after trying the source, got %d verses, %d queries, %v", len(verses), source.queries, err)
+	}
+	if _, err := cached.Query(ctx, "Genesis 2"); err == nil {
+		t.Fatalf("Expected an uncached query to fail while offline")
+	}
+
+	// Entries cached in an older format are fetched again however fresh
+	cache.ttl = time.Hour
+	source.offline = false
+	_, err = cache.db.Exec("UPDATE entries SET version = ?, fetched = CURRENT_TIMESTAMP", cacheFormat-1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = cached.Query(ctx, "Genesis 1")
+	if err != nil || source.queries != 4 {
+		t.Fatalf("Expected an entry in an older format to be fetched again, got %d queries, %v", source.queries, err)
+	}
+
+	// The least recently used entries are dropped to fit the size
+	cache.maxsize = 1
+	_, err = cached.Query(ctx, "Exodus 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Entries != 0 {
+		t.Fatalf("Expected every entry to be dropped to fit in 1 byte, %d left", stats.Entries)
+	}
+}
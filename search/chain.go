@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// ErrNoGrepper is returned when searching by words in a chain without a
+// source that can.
+var ErrNoGrepper = errors.New("Searching by words needs a downloaded translation")
+
+// Chain is a Searcher that asks each of its sources in turn, moving on to the
+// next when one fails or finds nothing. This lets a partial local copy be
+// filled in from the cache or from BibleGateway, query by query.
+type Chain struct {
+	translation string
+	sources     []Searcher
+}
+
+func NewChain(translation string, sources ...Searcher) *Chain {
+	return &Chain{translation, sources}
+}
+
+func (c *Chain) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	return first(ctx, c.sources, func(source Searcher) ([]model.Verse, error) {
+		return source.Query(ctx, query)
+	})
+}
+
+func (c *Chain) Booklist(ctx context.Context) ([]model.Book, error) {
+	return first(ctx, c.sources, func(source Searcher) ([]model.Book, error) {
+		return source.Booklist(ctx)
+	})
+}
+
+// Grep searches the first source that can search by words.
+func (c *Chain) Grep(ctx context.Context, words string, books BookRange, limit int) ([]model.Verse, error) {
+	for _, source := range c.sources {
+		if grepper, ok := source.(Grepper); ok {
+			return grepper.Grep(ctx, words, books, limit)
+		}
+	}
+	return nil, ErrNoGrepper
+}
+
+func (c *Chain) Translation() string {
+	return c.translation
+}
+
+// first returns the first result that isn't empty. If there isn't one, the
+// error of the last source to fail is returned, as it's the one of last
+// resort.
+func first[T any](ctx context.Context, sources []Searcher, f func(Searcher) ([]T, error)) ([]T, error) {
+	var lasterr error
+	for _, source := range sources {
+		result, err := f(source)
+		if err == nil && len(result) > 0 {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			lasterr = err
+		}
+	}
+	return nil, lasterr
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+
+	// Only has Genesis, like a partial local copy
+	partial := &fakesource{books: []model.Book{{Name: "Genesis", Chapters: 50}}}
+	limited := &limitedsource{partial}
+	remote := &countingsource{}
+	chain := NewChain("FAKE", limited, remote)
+
+	verses, err := chain.Query(ctx, "Genesis 1")
+	if err != nil || len(verses) != 3 || remote.queries != 0 {
+		t.Fatalf("Expected Genesis from the first source, got %d verses, %d remote queries, %v", len(verses), remote.queries, err)
+	}
+
+	verses, err = chain.Query(ctx, "Exodus 1")
+	if err != nil || len(verses) != 3 || remote.queries != 1 {
+		t.Fatalf("Expected Exodus from the second source, got %d verses, %d remote queries, %v", len(verses), remote.queries, err)
+	}
+
+	remote.offline = true
+	_, err = chain.Query(ctx, "Exodus 2")
+	if err == nil || err.Error() != "offline" {
+		t.Fatalf("Expected the error of the last source, got %v", err)
+	}
+
+	_, err = chain.Grep(ctx, "beginning", BookRange{}, 10)
+	if !errors.Is(err, ErrNoGrepper) {
+		t.Fatalf("Expected ErrNoGrepper, got %v", err)
+	}
+}
+
+// limitedsource finds nothing for books it doesn't have.
+type limitedsource struct {
+	*fakesource
+}
+
+func (l *limitedsource) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	verses, err := l.fakesource.Query(ctx, query)
+	if err != nil || len(verses) == 0 {
+		return verses, err
+	}
+	for _, book := range l.books {
+		if book.Name == verses[0].Book {
+			return verses, nil
+		}
+	}
+	return nil, nil
+}
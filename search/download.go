@@ -0,0 +1,472 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// Download fills a local copy of a translation from BibleGateway. Verses are
+// written to a temporary database alongside the real one, recording each
+// chapter as it completes so that an interrupted download can be resumed. The
+// real database is only replaced once every chapter has been downloaded.
+type Download struct {
+	source Searcher
+	books  []model.Book
+
+	db      *sqlx.DB
+	path    string
+	partial string
+	done    map[chapterkey]bool
+	closed  bool
+}
+
+type chapterkey struct {
+	book    string
+	chapter int
+}
+
+// downloadSource is recorded as where downloaded translations came from.
+const downloadSource = "https://www.biblegateway.com"
+
+// retries is how many times a chapter is tried before giving up.
+const retries = 5
+
+// backoff is how long a chapter waits before being tried again when the server
+// doesn't say how long to wait.
+const backoff = 5 * time.Second
+
+// Progress is reported each time a chapter has been saved.
+type Progress struct {
+	Book    string
+	Chapter int
+	Done    int
+	Total   int
+}
+
+// NewDownload prepares a download of the translation source searches. With
+// resume, chapters already downloaded by an earlier interrupted attempt are
+// kept, otherwise the download starts over.
+func NewDownload(ctx context.Context, source Searcher, resume bool) (*Download, error) {
+	sqlpath, err := LocalPath(source.Translation())
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(path.Dir(sqlpath), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDownload(ctx, source, sqlpath, resume)
+}
+
+func newDownload(ctx context.Context, source Searcher, sqlpath string, resume bool) (*Download, error) {
+	books, err := source.Booklist(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, fmt.Errorf("No books found for translation: %s", source.Translation())
+	}
+
+	partial := sqlpath + ".part"
+	if !resume {
+		err = os.Remove(partial)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	db, err := sqlx.Open("sqlite3", partial)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Download{
+		source:  source,
+		books:   books,
+		db:      db,
+		path:    sqlpath,
+		partial: partial,
+		done:    map[chapterkey]bool{},
+	}
+
+	err = d.init()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *Download) init() error {
+	err := migrate(d.db, d.source.Translation())
+	if err != nil {
+		return err
+	}
+
+	err = d.writeMeta()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`
+		CREATE TABLE IF NOT EXISTS progress (
+		book TEXT,
+		chapter INTEGER,
+		PRIMARY KEY (book, chapter)
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var done []struct {
+		Book    string `db:"book"`
+		Chapter int    `db:"chapter"`
+	}
+	err = d.db.Select(&done, "SELECT book, chapter FROM progress")
+	if err != nil {
+		return err
+	}
+	for _, c := range done {
+		d.done[chapterkey{c.Book, c.Chapter}] = true
+	}
+
+	// Only verses of chapters recorded as done are kept, so that nothing is
+	// downloaded twice if the progress was lost, such as when finishing failed
+	// after the progress was dropped.
+	_, err = d.db.Exec("DELETE FROM verses WHERE NOT EXISTS (SELECT 1 FROM progress p WHERE p.book = verses.book AND p.chapter = verses.chapter)")
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec("DELETE FROM notes WHERE verse NOT IN (SELECT id FROM verses)")
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec("DELETE FROM spans WHERE verse NOT IN (SELECT id FROM verses)")
+	return err
+}
+
+// writeMeta records the booklist being downloaded, where it's being
+// downloaded from and the full title of the translation if it's known.
+func (d *Download) writeMeta() error {
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM books")
+	if err != nil {
+		return err
+	}
+
+	for i, book := range d.books {
+		_, err = tx.Exec("INSERT INTO books (position, name, chapters) VALUES (?, ?, ?)", i+1, book.Name, book.Chapters)
+		if err != nil {
+			return err
+		}
+	}
+
+	var title string
+	if versions, ok := CachedVersions(); ok {
+		version, _ := FindVersion(versions, d.source.Translation())
+		title = version.Name
+	}
+
+	_, err = tx.Exec("UPDATE meta SET source = ?, title = ?", downloadSource, title)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *Download) Books() []model.Book {
+	return d.books
+}
+
+// Done reports whether a chapter has already been downloaded.
+func (d *Download) Done(book string, chapter int) bool {
+	return d.done[chapterkey{book, chapter}]
+}
+
+// Total returns how many chapters there are in the translation.
+func (d *Download) Total() int {
+	var total int
+	for _, book := range d.books {
+		total += book.Chapters
+	}
+	return total
+}
+
+// Remaining returns how many chapters are left to download.
+func (d *Download) Remaining() int {
+	return d.Total() - len(d.done)
+}
+
+// Run downloads every remaining chapter, with workers fetching chapters in
+// parallel while sharing limiter so that BibleGateway isn't overwhelmed. The
+// chapters are still saved in canonical order, since local range queries
+// depend on it. progress, if given, is called after each chapter is saved.
+// Cancelling ctx stops the download once the chapters in flight are dropped,
+// keeping every chapter saved so far.
+func (d *Download) Run(ctx context.Context, workers int, limiter *Limiter, progress func(Progress)) error {
+	type job struct {
+		index   int
+		book    string
+		chapter int
+	}
+	type result struct {
+		job
+		verses []model.Verse
+		err    error
+	}
+
+	var jobs []job
+	total := d.Total()
+	for _, book := range d.books {
+		for chapter := 1; chapter <= book.Chapters; chapter++ {
+			if !d.Done(book.Name, chapter) {
+				jobs = append(jobs, job{len(jobs), book.Name, chapter})
+			}
+		}
+	}
+
+	// Workers still fetching when Run returns are cancelled and waited for,
+	// so nothing is left touching the source afterwards.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan job)
+	results := make(chan result)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		defer close(queue)
+		for _, j := range jobs {
+			select {
+			case queue <- j:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for range max(1, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				verses, err := d.fetch(ctx, j.book, j.chapter, limiter)
+				select {
+				case results <- result{j, verses, err}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	// Results arrive in whatever order the workers finish them, so they are
+	// held until every chapter before them has been saved.
+	pending := map[int]result{}
+	for next := 0; next < len(jobs); {
+		r := <-results
+		if r.err != nil {
+			return r.err
+		}
+		pending[r.index] = r
+
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			err := d.Save(r.book, r.chapter, r.verses)
+			if err != nil {
+				return err
+			}
+
+			if progress != nil {
+				progress(Progress{Book: r.book, Chapter: r.chapter, Done: total - d.Remaining(), Total: total})
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetch downloads a single chapter, retrying in case of a network blip. Only
+// when the server asks for requests to slow down is every worker paused,
+// otherwise just this one backs off.
+func (d *Download) fetch(ctx context.Context, book string, chapter int, limiter *Limiter) ([]model.Verse, error) {
+	var err error
+	for attempt := range retries {
+		if attempt > 0 {
+			wait := time.Duration(attempt) * backoff
+			var serr *StatusError
+			if errors.As(err, &serr) && serr.RetryAfter > 0 {
+				wait = serr.RetryAfter
+			}
+
+			if serr != nil && serr.Throttled() {
+				limiter.Pause(wait)
+			} else {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				}
+			}
+		}
+		err = limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var verses []model.Verse
+		verses, err = d.source.Query(ctx, fmt.Sprintf("%s %d", book, chapter))
+		if err == nil {
+			return verses, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !retryable(err) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%s %d: %w", book, chapter, err)
+}
+
+// retryable reports whether a failed query might succeed if it's tried again.
+// Pages that can't be parsed will be the same next time, so only network
+// errors and the statuses the server sends while struggling are retried.
+func retryable(err error) bool {
+	if errors.Is(err, ErrUnexpectedMarkup) {
+		return false
+	}
+
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return serr.Temporary() || serr.Throttled()
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Save writes the verses of a chapter and marks it as done in a single
+// transaction, so a chapter is either fully saved or not at all.
+func (d *Download) Save(book string, chapter int, verses []model.Verse) error {
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, verse := range verses {
+		result, err := tx.Exec("insert into verses (book, chapter, number, part, text, title, paragraph, poetry, indent) values (?, ?, ?, ?, ?, ?, ?, ?, ?)", book, verse.Chapter, verse.Number, verse.Part, verse.Text, verse.Title, verse.Paragraph, verse.Poetry, verse.Indent)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, note := range verse.Notes {
+			_, err = tx.Exec("INSERT INTO notes (verse, kind, marker, position, text, refs) VALUES (?, ?, ?, ?, ?, ?)", id, note.Kind, note.Marker, note.Offset, note.Text, note.References)
+			if err != nil {
+				return err
+			}
+		}
+		for _, span := range verse.Spans {
+			_, err = tx.Exec("INSERT INTO spans (verse, kind, position, length) VALUES (?, ?, ?, ?)", id, span.Kind, span.Offset, span.Length)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO progress (book, chapter) VALUES (?, ?)", book, chapter)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	d.done[chapterkey{book, chapter}] = true
+	return nil
+}
+
+// Finish builds the search index and replaces the real database with the
+// downloaded one. It fails if any chapter is still missing. The returned
+// error is ErrNoFulltext when everything else succeeded but the index
+// couldn't be built.
+func (d *Download) Finish() error {
+	if remaining := d.Remaining(); remaining > 0 {
+		return fmt.Errorf("%d chapters have not been downloaded", remaining)
+	}
+
+	_, err := d.db.Exec("DROP TABLE progress")
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec("UPDATE meta SET downloaded = CURRENT_TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
+	ferr := CreateFulltextIndex(d.db)
+	if ferr != nil && !errors.Is(ferr, ErrNoFulltext) {
+		return ferr
+	}
+
+	err = d.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(d.partial, d.path)
+	if err != nil {
+		return err
+	}
+
+	return ferr
+}
+
+// Close stops the download, keeping what has been downloaded so far to be
+// resumed later.
+func (d *Download) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.db.Close()
+}
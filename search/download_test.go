@@ -0,0 +1,231 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// fakesource serves three verses for every chapter of its books after a short
+// random delay, the second with a footnote and its book in red letters,
+// failing once it reaches the chapter in fail.
+type fakesource struct {
+	books []model.Book
+	fail  string
+}
+
+func (f *fakesource) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+	if query == f.fail {
+		return nil, &StatusError{Message: "unable to retrieve passage", StatusCode: 404}
+	}
+
+	split := strings.LastIndex(query, " ")
+	chapter, err := strconv.Atoi(query[split+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var verses []model.Verse
+	for number := 1; number <= 3; number++ {
+		verses = append(verses, model.Verse{Book: query[:split], Chapter: chapter, Number: number, Part: 1, Text: query})
+	}
+	verses[1].Notes = []model.Note{{Kind: model.Footnote, Marker: "a", Offset: split, Text: query}}
+	verses[1].Spans = []model.Span{{Kind: model.WordsOfJesus, Offset: 0, Length: split}}
+	return verses, nil
+}
+
+func (f *fakesource) Booklist(ctx context.Context) ([]model.Book, error) {
+	return f.books, nil
+}
+
+func (f *fakesource) Translation() string {
+	return "FAKE"
+}
+
+func TestDownloadOrder(t *testing.T) {
+	source := &fakesource{
+		books: []model.Book{{Name: "Genesis", Chapters: 12}, {Name: "Exodus", Chapters: 9}, {Name: "Jude", Chapters: 1}},
+		fail:  "Exodus 4",
+	}
+	sqlpath := path.Join(t.TempDir(), "FAKE.sql")
+	limiter := NewLimiter(0, 1)
+
+	d, err := newDownload(context.Background(), source, sqlpath, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var serr *StatusError
+	err = d.Run(context.Background(), 4, limiter, nil)
+	if !errors.As(err, &serr) {
+		t.Fatalf("Expected the download to fail at %s, got %v", source.fail, err)
+	}
+	if d.Finish() == nil {
+		t.Fatalf("Expected an incomplete download to not finish")
+	}
+	d.Close()
+
+	// Resume without the failure
+	source.fail = ""
+	d, err = newDownload(context.Background(), source, sqlpath, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Remaining() == 22 {
+		t.Fatalf("Expected some chapters to have been kept for resuming")
+	}
+
+	err = d.Run(context.Background(), 4, limiter, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = d.Finish()
+	if err != nil && !errors.Is(err, ErrNoFulltext) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db, err := sqlx.Open("sqlite3", sqlpath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	var texts []string
+	err = db.Select(&texts, "SELECT text FROM verses ORDER BY id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var expected []string
+	for _, book := range source.books {
+		for chapter := 1; chapter <= book.Chapters; chapter++ {
+			for range 3 {
+				expected = append(expected, fmt.Sprintf("%s %d", book.Name, chapter))
+			}
+		}
+	}
+
+	if len(texts) != len(expected) {
+		t.Fatalf("Expected %d verses, got %d", len(expected), len(texts))
+	}
+	for i := range texts {
+		if texts[i] != expected[i] {
+			t.Fatalf("Verse %d out of order: expected %s, got %s", i, expected[i], texts[i])
+		}
+	}
+
+	local := &Local{db, "FAKE"}
+	books, err := local.Booklist(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(books, source.books) {
+		t.Fatalf("Expected booklist %v, got %v", source.books, books)
+	}
+
+	verses, err := local.Query(context.Background(), "Exodus 4:1-2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(verses) != 2 || verses[0].Notes != nil || len(verses[1].Notes) != 1 || verses[1].Notes[0].Text != "Exodus 4" {
+		t.Fatalf("Expected the footnote of Exodus 4:2, got %+v", verses)
+	}
+	if verses[0].Spans != nil || !reflect.DeepEqual(verses[1].Spans, []model.Span{{Kind: model.WordsOfJesus, Offset: 0, Length: 6}}) {
+		t.Fatalf("Expected the red letters of Exodus 4:2, got %+v", verses)
+	}
+
+	meta, err := local.Meta()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meta.Translation != "FAKE" || !meta.Downloaded.Valid {
+		t.Fatalf("Unexpected metadata after downloading: %+v", meta)
+	}
+}
+
+func TestDownloadCancel(t *testing.T) {
+	source := &fakesource{books: []model.Book{{Name: "Genesis", Chapters: 50}}}
+	d, err := newDownload(context.Background(), source, path.Join(t.TempDir(), "FAKE.sql"), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = d.Run(ctx, 4, NewLimiter(time.Millisecond, 1), func(p Progress) {
+		if p.Done == 10 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the download to be cancelled, got %v", err)
+	}
+	if d.Remaining() == 0 || d.Remaining() > 40 {
+		t.Fatalf("Expected the chapters saved before cancelling to be kept, %d remaining", d.Remaining())
+	}
+}
+
+// brokensource serves pages that can't be parsed, counting how often it's
+// asked for them.
+type brokensource struct {
+	fakesource
+	queries atomic.Int32
+}
+
+func (b *brokensource) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	b.queries.Add(1)
+	return nil, &MarkupError{Reason: "no passage found"}
+}
+
+func TestDownloadMarkupError(t *testing.T) {
+	source := &brokensource{fakesource: fakesource{books: []model.Book{{Name: "Jude", Chapters: 1}}}}
+	d, err := newDownload(context.Background(), source, path.Join(t.TempDir(), "FAKE.sql"), false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	err = d.Run(context.Background(), 1, NewLimiter(0, 1), nil)
+	if !errors.Is(err, ErrUnexpectedMarkup) {
+		t.Fatalf("Expected the markup error, got %v", err)
+	}
+	if queries := source.queries.Load(); queries != 1 {
+		t.Fatalf("Expected the chapter to be queried once, got %d", queries)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&MarkupError{Reason: "no passage found"}, false},
+		{errors.New("No books found"), false},
+		{&StatusError{StatusCode: 404}, false},
+		{&StatusError{StatusCode: 502}, true},
+		{&StatusError{StatusCode: 429}, true},
+		{&url.Error{Op: "Get", URL: "https://www.biblegateway.com", Err: context.DeadlineExceeded}, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+	}
+
+	for _, test := range tests {
+		if retryable(test.err) != test.retryable {
+			t.Errorf("Expected retryable(%v) to be %v", test.err, test.retryable)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+// ErrNoFulltext is returned when a local translation was downloaded without a
+// full-text index, either by an older bgate or by a build without FTS5 support.
+var ErrNoFulltext = errors.New("no full-text index for this translation, download it again with a build of bgate that includes the sqlite_fts5 tag")
+
+// Grepper is implemented by searchers that can search verses by their words
+// rather than by reference.
+type Grepper interface {
+	Grep(ctx context.Context, words string, books BookRange, limit int) ([]model.Verse, error)
+}
+
+// FulltextSupported reports whether this build of bgate can create full-text
+// indexes, which needs SQLite compiled with FTS5 by the sqlite_fts5 tag.
+func FulltextSupported() bool {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE VIRTUAL TABLE probe USING fts5(text)")
+	return err == nil
+}
+
+// CreateFulltextIndex builds the FTS5 index over the text of every verse. It
+// should be run once all of the verses have been inserted.
+func CreateFulltextIndex(db *sqlx.DB) error {
+	_, err := db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS verses_fts USING fts5(
+			text,
+			content='verses',
+			content_rowid='id',
+			tokenize='porter unicode61'
+		)`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return ErrNoFulltext
+		}
+		return err
+	}
+
+	_, err = db.Exec("INSERT INTO verses_fts(verses_fts) VALUES('rebuild')")
+	return err
+}
+
+// Grep returns the verses matching words, best match first. Words are matched
+// as separate terms, a quoted "phrase" must match exactly, and a trailing * on a
+// word matches any word starting with it.
+func (l *Local) Grep(ctx context.Context, words string, books BookRange, limit int) ([]model.Verse, error) {
+	match, err := ftsquery(words)
+	if err != nil {
+		return nil, err
+	}
+
+	where := "verses_fts MATCH ?"
+	args := []any{match}
+	if books.First != "" {
+		where += " AND v.id >= (SELECT min(id) FROM verses WHERE book = ?) AND v.id <= (SELECT max(id) FROM verses WHERE book = ?)"
+		args = append(args, books.First, books.Last)
+	}
+	args = append(args, limit)
+
+	var verses []model.Verse
+	err = l.db.SelectContext(ctx, &verses, fmt.Sprintf(`
+		SELECT v.book, v.chapter, v.number, v.part, v.text, v.title
+		FROM verses_fts JOIN verses v ON v.id = verses_fts.rowid
+		WHERE %s
+		ORDER BY rank
+		LIMIT ?`, where), args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "no such module") {
+			return nil, ErrNoFulltext
+		}
+		return nil, err
+	}
+
+	return verses, nil
+}
+
+// ftsquery turns user input into an FTS5 query, quoting every term so that
+// punctuation can't be read as query syntax. Phrases, trailing * prefixes and
+// the AND, OR and NOT operators are kept.
+func ftsquery(words string) (string, error) {
+	var terms []string
+
+	runes := []rune(strings.TrimSpace(words))
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == ' ' || runes[i] == '\t' {
+			continue
+		}
+
+		var term []rune
+		if runes[i] == '"' {
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				term = append(term, runes[i])
+				i++
+			}
+			if i == len(runes) {
+				return "", errors.New("Unterminated phrase in search")
+			}
+		} else {
+			for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' && runes[i] != '"' {
+				term = append(term, runes[i])
+				i++
+			}
+			i--
+		}
+
+		value := string(term)
+		if value == "AND" || value == "OR" || value == "NOT" {
+			terms = append(terms, value)
+			continue
+		}
+
+		prefix := strings.HasSuffix(value, "*")
+		value = strings.TrimRight(value, "*")
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		value = `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		if prefix {
+			value += "*"
+		}
+		terms = append(terms, value)
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("Nothing to search for")
+	}
+	return strings.Join(terms, " "), nil
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+func TestFtsQuery(t *testing.T) {
+	tests := []struct {
+		words    string
+		expected string
+	}{
+		{"mustard seed", `"mustard" "seed"`},
+		{`"mustard seed"`, `"mustard seed"`},
+		{"mustar*", `"mustar"*`},
+		{"faith OR hope NOT fear", `"faith" OR "hope" NOT "fear"`},
+		{"don't-fear", `"don't-fear"`},
+	}
+
+	for _, test := range tests {
+		output, err := ftsquery(test.words)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", test.words, err)
+		}
+		if output != test.expected {
+			t.Fatalf("Unexpected query for %q:\nExpected: %s\nActual: %s", test.words, test.expected, output)
+		}
+	}
+
+	for _, words := range []string{"", "  ", `"unterminated`, "*"} {
+		if _, err := ftsquery(words); err == nil {
+			t.Fatalf("Expected error for %q", words)
+		}
+	}
+}
+
+func TestParseBookRange(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected BookRange
+	}{
+		{"Romans-Jude", BookRange{"Romans", "Jude"}},
+		{"1cor - 2 cor", BookRange{"1 Corinthians", "2 Corinthians"}},
+		{"gen", BookRange{"Genesis", "Genesis"}},
+	}
+
+	for _, test := range tests {
+		output, err := ParseBookRange(test.query)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", test.query, err)
+		}
+		if output != test.expected {
+			t.Fatalf("Unexpected range for %q: %v", test.query, output)
+		}
+	}
+
+	for _, query := range []string{"", "romans 1", "romans-", "romans-jude 2"} {
+		if _, err := ParseBookRange(query); err == nil {
+			t.Fatalf("Expected error for %q", query)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", path.Join(t.TempDir(), "FAKE.sql"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+	err = migrate(db, "FAKE")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	verses := []model.Verse{
+		{Book: "John", Chapter: 3, Number: 16, Text: "For God so loved the world that he gave his one and only Son"},
+		{Book: "1 Corinthians", Chapter: 13, Number: 4, Text: "Love is patient, love is kind"},
+		{Book: "1 John", Chapter: 4, Number: 8, Text: "Whoever does not love does not know God, because God is love"},
+		{Book: "Jude", Chapter: 1, Number: 21, Text: "keep yourselves in God's love"},
+	}
+	for _, verse := range verses {
+		_, err = db.Exec("INSERT INTO verses (book, chapter, number, part, text, title) VALUES (?, ?, ?, 1, ?, '')", verse.Book, verse.Chapter, verse.Number, verse.Text)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	err = CreateFulltextIndex(db)
+	if errors.Is(err, ErrNoFulltext) {
+		t.Skip("Built without the sqlite_fts5 tag")
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	local := &Local{db, "FAKE"}
+	tests := []struct {
+		words    string
+		in       string
+		expected []string
+	}{
+		// Stemmed, with the verse saying it most often and most briefly first
+		{"love", "", []string{"1 Corinthians 13:4", "1 John 4:8", "Jude 1:21", "John 3:16"}},
+		{`"god is love"`, "", []string{"1 John 4:8"}},
+		{"pati*", "", []string{"1 Corinthians 13:4"}},
+		{"love", "1 Corinthians-1 John", []string{"1 Corinthians 13:4", "1 John 4:8"}},
+		{"love", "jude", []string{"Jude 1:21"}},
+	}
+
+	for _, test := range tests {
+		var books BookRange
+		if test.in != "" {
+			books, err = ParseBookRange(test.in)
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", test.in, err)
+			}
+		}
+
+		found, err := local.Grep(context.Background(), test.words, books, 10)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", test.words, err)
+		}
+		var references []string
+		for _, verse := range found {
+			references = append(references, fmt.Sprintf("%s %d:%d", verse.Book, verse.Chapter, verse.Number))
+		}
+		if !slices.Equal(references, test.expected) {
+			t.Errorf("Expected %q in %q to find %v, got %v", test.words, test.in, test.expected, references)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path"
 
@@ -10,14 +12,31 @@ import (
 	"github.com/nilptrderef/bgate/reader/model"
 )
 
-func TranslationHasLocal(translation string) (bool, error) {
+// datadir returns the directory translations and everything else bgate keeps
+// are stored in.
+func datadir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return false, err
+		return "", err
+	}
+	return path.Join(home, ".bgate"), nil
+}
+
+// LocalPath returns where the local copy of a translation is kept, whether or
+// not it has been downloaded.
+func LocalPath(translation string) (string, error) {
+	bgatepath, err := datadir()
+	if err != nil {
+		return "", err
 	}
+	return path.Join(bgatepath, fmt.Sprintf("%s.sql", translation)), nil
+}
 
-	bgatepath := path.Join(home, ".bgate")
-	sqlpath := path.Join(bgatepath, fmt.Sprintf("%s.sql", translation))
+func TranslationHasLocal(translation string) (bool, error) {
+	sqlpath, err := LocalPath(translation)
+	if err != nil {
+		return false, err
+	}
 
 	_, err = os.Stat(sqlpath)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
@@ -32,39 +51,136 @@ type Local struct {
 	translation string
 }
 
+// NewLocal opens the downloaded copy of translation, failing if there isn't
+// one. It's opened read-only unless its schema needs migrating first.
 func NewLocal(translation string) (*Local, error) {
-	home, err := os.UserHomeDir()
+	sqlpath, err := LocalPath(translation)
 	if err != nil {
 		return nil, err
 	}
-	bgatepath := path.Join(home, ".bgate")
-	sqlpath := path.Join(bgatepath, fmt.Sprintf("%s.sql", translation))
-	db, err := sqlx.Open("sqlite3", sqlpath)
+	return openLocal(sqlpath, translation)
+}
+
+func openLocal(sqlpath string, translation string) (*Local, error) {
+	_, err := os.Stat(sqlpath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("No local copy of %s found (%w)", translation, os.ErrNotExist)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &Local{db, translation}, nil
-}
 
-func (l *Local) Query(query string) ([]model.Verse, error) {
-	query, err := parsequery(query)
+	db, err := sqlx.Open("sqlite3", readonly(sqlpath))
 	if err != nil {
 		return nil, err
 	}
-	query = fmt.Sprintf("SELECT book, chapter, number, part, text, title FROM verses WHERE %s", query)
 
-	var verses []model.Verse
-	err = l.db.Select(&verses, query)
+	version, err := schemaVersion(db)
+	if err == nil && version < latestVersion {
+		db.Close()
+		db, err = sqlx.Open("sqlite3", sqlpath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err == nil {
+		err = migrate(db, translation)
+	}
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
+	return &Local{db, translation}, nil
+}
+
+// readonly returns the name to open a database by without ever writing to it.
+func readonly(sqlpath string) string {
+	return (&url.URL{Scheme: "file", Path: sqlpath, RawQuery: "mode=ro"}).String()
+}
+
+func (l *Local) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	ranges, err := ParseReference(query)
+	if err != nil {
+		return nil, err
+	}
+	return l.QueryRanges(ctx, ranges)
+}
+
+// QueryRanges looks up each range in turn, returning the verses in the order
+// the ranges were given.
+func (l *Local) QueryRanges(ctx context.Context, ranges []Range) ([]model.Verse, error) {
+	var verses []model.Verse
+	for _, r := range ranges {
+		where, args := rangequery(r)
+
+		var rverses []struct {
+			ID int `db:"id"`
+			model.Verse
+		}
+		err := l.db.SelectContext(ctx, &rverses, "SELECT id, book, chapter, number, part, text, title, paragraph, poetry, indent FROM verses WHERE "+where+" ORDER BY id", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		var notes []struct {
+			Verse int `db:"verse"`
+			model.Note
+		}
+		err = l.db.SelectContext(ctx, &notes, "SELECT verse, kind, marker, position, text, refs FROM notes WHERE verse IN (SELECT id FROM verses WHERE "+where+") ORDER BY rowid", args...)
+		if err != nil {
+			return nil, err
+		}
+		vnotes := map[int][]model.Note{}
+		for _, note := range notes {
+			vnotes[note.Verse] = append(vnotes[note.Verse], note.Note)
+		}
 
+		var spans []struct {
+			Verse int `db:"verse"`
+			model.Span
+		}
+		err = l.db.SelectContext(ctx, &spans, "SELECT verse, kind, position, length FROM spans WHERE verse IN (SELECT id FROM verses WHERE "+where+") ORDER BY rowid", args...)
+		if err != nil {
+			return nil, err
+		}
+		vspans := map[int][]model.Span{}
+		for _, span := range spans {
+			vspans[span.Verse] = append(vspans[span.Verse], span.Span)
+		}
+
+		for _, verse := range rverses {
+			verse.Notes = vnotes[verse.ID]
+			verse.Spans = vspans[verse.ID]
+			verses = append(verses, verse.Verse)
+		}
+	}
 	return verses, nil
 }
 
-func (l *Local) Booklist() ([]model.Book, error) {
+// rangequery builds a parameterized condition matching every verse between the first
+// row of the start reference and the last row of the end reference. This relies
+// on the verses having been inserted in canonical order.
+func rangequery(r Range) (string, []any) {
+	bound := func(ref Reference, order string) (string, []any) {
+		where := "book = ? AND chapter = ?"
+		args := []any{ref.Book, ref.Chapter}
+		if ref.Verse != 0 {
+			where += " AND number = ?"
+			args = append(args, ref.Verse)
+		}
+		return fmt.Sprintf("(SELECT id FROM verses WHERE %s ORDER BY id %s LIMIT 1)", where, order), args
+	}
+
+	start, sargs := bound(r.Start, "ASC")
+	end, eargs := bound(r.End, "DESC")
+
+	where := fmt.Sprintf("id >= %s AND id <= %s", start, end)
+	return where, append(sargs, eargs...)
+}
+
+func (l *Local) Booklist(ctx context.Context) ([]model.Book, error) {
 	var books []model.Book
-	err := l.db.Select(&books, "SELECT distinct(book) as name, max(chapter) as chapters FROM verses group by book order by id")
+	err := l.db.SelectContext(ctx, &books, "SELECT name, chapters FROM books ORDER BY position")
 	if err != nil {
 		return nil, err
 	}
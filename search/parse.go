@@ -2,7 +2,7 @@ package search
 
 import (
 	"errors"
-	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -10,14 +10,12 @@ import (
 type tokentype int
 
 var (
-	token_number tokentype = 0
-	token_word   tokentype = 1
-	token_colon  tokentype = 2
-	token_dash   tokentype = 3
-	// TODO:
-	// token_comma tokentype = 4
-	// TODO:
-	// token_semicolon tokentype = 5
+	token_number    tokentype = 0
+	token_word      tokentype = 1
+	token_colon     tokentype = 2
+	token_dash      tokentype = 3
+	token_comma     tokentype = 4
+	token_semicolon tokentype = 5
 )
 
 type token struct {
@@ -49,6 +47,10 @@ func tokenize(query string) ([]token, error) {
 			tokens = append(tokens, token{_type: token_colon, value: ":"})
 		} else if runes[i] == '-' {
 			tokens = append(tokens, token{_type: token_dash, value: "-"})
+		} else if runes[i] == ',' {
+			tokens = append(tokens, token{_type: token_comma, value: ","})
+		} else if runes[i] == ';' {
+			tokens = append(tokens, token{_type: token_semicolon, value: ";"})
 		} else {
 			return nil, errors.New("Invalid character when tokenizing query")
 		}
@@ -56,136 +58,218 @@ func tokenize(query string) ([]token, error) {
 	return tokens, nil
 }
 
+// parsebook reads a book name, which may start with a number and span several
+// words, trying the longest run of words first so that "song of solomon"
+// isn't read as "song".
 func parsebook(tokens []token) (string, []token, error) {
-	book := ""
-
 	if len(tokens) == 0 {
-		return book, tokens, errors.New("No book found")
+		return "", tokens, errors.New("No book found")
 	}
 
-	if tokens[0]._type == token_word {
-		book = tokens[0].value
-		if book, ok := abbreviations[book]; ok {
-			return book, tokens[1:], nil
-		}
-	}
+	var prefix string
+	start := 0
 	if tokens[0]._type == token_number {
-		book = tokens[0].value
+		prefix = tokens[0].value
+		start = 1
+	}
 
-		if !(len(tokens) > 1 && tokens[1]._type == token_word) {
-			return book, tokens, errors.New("invalid token in book parsing")
-		}
+	end := start
+	for end < len(tokens) && tokens[end]._type == token_word {
+		end++
+	}
 
-		book += tokens[1].value
-		if book, ok := abbreviations[book]; ok {
-			return book, tokens[2:], nil
+	for i := end; i > start; i-- {
+		name := prefix
+		for _, tok := range tokens[start:i] {
+			name += tok.value
+		}
+		if book, ok := abbreviations[name]; ok {
+			return book, tokens[i:], nil
 		}
 	}
 
-	return book, tokens, errors.New("invalid token in book parsing")
+	return "", tokens, errors.New("invalid token in book parsing")
 }
 
-func parsechapter(tokens []token) (string, []token, error) {
+func parsenumber(tok token) (int, error) {
+	n, err := strconv.Atoi(tok.value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		return 0, errors.New("Numbers in a reference start at 1")
+	}
+	return n, nil
+}
+
+func parsechapter(tokens []token) (int, []token, error) {
 	if len(tokens) == 0 {
-		return "", tokens, errors.New("No chapter found")
+		return 0, tokens, errors.New("No chapter found")
 	}
 	if tokens[0]._type != token_number {
-		return "", tokens, errors.New("Invalid chapter")
+		return 0, tokens, errors.New("Invalid chapter")
+	}
+	chapter, err := parsenumber(tokens[0])
+	if err != nil {
+		return 0, tokens, errors.Join(errors.New("Invalid chapter"), err)
 	}
-	return tokens[0].value, tokens[1:], nil
+	return chapter, tokens[1:], nil
 }
 
-func parseverse(tokens []token) (string, []token, error) {
+func parseverse(tokens []token) (int, []token, error) {
 	if len(tokens) == 0 {
-		return "", tokens, nil
+		return 0, tokens, nil
 	}
 	if tokens[0]._type != token_colon {
-		return "", tokens, nil
+		return 0, tokens, nil
 	}
 	if len(tokens) == 1 {
-		return "", tokens, errors.New("No verse found")
+		return 0, tokens, errors.New("No verse found")
 	}
 	if tokens[1]._type != token_number {
-		return "", tokens, errors.New("Invalid verse")
+		return 0, tokens, errors.New("Invalid verse")
 	}
-	return tokens[1].value, tokens[2:], nil
+	verse, err := parsenumber(tokens[1])
+	if err != nil {
+		return 0, tokens, errors.Join(errors.New("Invalid verse"), err)
+	}
+	return verse, tokens[2:], nil
 }
 
-func parsepart(tokens []token) (string, []token, error) {
+func parsepart(tokens []token, prev *Reference, separator tokentype) (Range, []token, error) {
 	book, tokens, err := parsebook(tokens)
+	inherited := false
+	if err != nil {
+		if prev == nil {
+			return Range{}, tokens, err
+		}
+		book = prev.Book
+		inherited = true
+	}
+
+	start := Reference{Book: book}
+	if inherited && separator == token_comma && prev.Verse != 0 && !(len(tokens) > 1 && tokens[1]._type == token_colon) {
+		// After a verse, a comma continues with verses of the same chapter
+		if len(tokens) == 0 || tokens[0]._type != token_number {
+			return Range{}, tokens, errors.New("Invalid verse")
+		}
+		start.Chapter = prev.Chapter
+		start.Verse, err = parsenumber(tokens[0])
+		if err != nil {
+			return Range{}, tokens, errors.Join(errors.New("Invalid verse"), err)
+		}
+		tokens = tokens[1:]
+	} else {
+		start.Chapter, tokens, err = parsechapter(tokens)
+		if err != nil {
+			return Range{}, tokens, err
+		}
+
+		start.Verse, tokens, err = parseverse(tokens)
+		if err != nil {
+			return Range{}, tokens, err
+		}
+	}
+
+	if len(tokens) == 0 || tokens[0]._type != token_dash {
+		return Range{Start: start, End: start}, tokens, nil
+	}
+	tokens = tokens[1:]
+
+	end := Reference{}
+	end.Book, tokens, err = parsebook(tokens)
 	if err != nil {
-		return "", tokens, err
+		end.Book = book
 	}
 
-	chapter, tokens, err := parsechapter(tokens)
+	var number int
+	number, tokens, err = parsechapter(tokens)
 	if err != nil {
-		return "", tokens, err
+		return Range{}, tokens, errors.Join(errors.New("failed in second chapter parse"), err)
 	}
-	part := fmt.Sprintf("book = '%s' and chapter = %s", book, chapter)
 
-	verse, tokens, err := parseverse(tokens)
+	end.Verse, tokens, err = parseverse(tokens)
 	if err != nil {
-		return "", tokens, err
+		return Range{}, tokens, err
 	}
-	if verse != "" {
-		part += fmt.Sprintf(" and number = %s", verse)
+
+	if start.Verse != 0 && end.Verse == 0 && end.Book == book {
+		// Continuation of chapter verse->verse
+		end.Chapter = start.Chapter
+		end.Verse = number
+	} else {
+		// Range across chapters possibly
+		end.Chapter = number
 	}
 
-	if len(tokens) > 0 && tokens[0]._type == token_dash {
-		tokens = tokens[1:]
-		vrange := fmt.Sprintf("id >= (select id from verses where %s order by id limit 1)", part)
+	return Range{Start: start, End: end}, tokens, nil
+}
 
-		var newbook string
-		newbook, tokens, err = parsebook(tokens)
-		if err != nil {
-			newbook = book
-		}
+// ParseReference parses a reference list such as "John 3:16,18; Rom 5:8-10; Ps 23"
+// into one range per passage, in the order they were given. A comma carries the
+// book forward, along with the chapter when the previous passage ended on a verse,
+// while a semicolon only carries the book forward.
+func ParseReference(query string) ([]Range, error) {
+	query = strings.TrimSpace(query)
+	query = strings.ToLower(query)
 
-		var newchapter string
-		newchapter, tokens, err = parsechapter(tokens)
-		if err != nil {
-			return "", tokens, errors.Join(errors.New("failed in second chapter parse"), err)
-		}
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
 
-		var newverse string
-		newverse, tokens, err = parseverse(tokens)
+	var ranges []Range
+	var prev *Reference
+	separator := token_semicolon
+	for {
+		r, rest, err := parsepart(tokens, prev, separator)
 		if err != nil {
-			return "", tokens, err
+			return nil, err
 		}
+		ranges = append(ranges, r)
+		prev = &r.End
 
-		if verse != "" && newverse != "" {
-			// Range across chapters possibly
-			part = fmt.Sprintf("book = '%s' and chapter = %s and number = %s", newbook, newchapter, newverse)
-		} else if verse != "" && newverse == "" {
-			// Continuation of chapter verse->verse
-			part = fmt.Sprintf("book = '%s' and chapter = %s and number = %s", newbook, chapter, newchapter)
-		} else {
-			part = fmt.Sprintf("book = '%s' and chapter = %s", newbook, newchapter)
-			if newverse != "" {
-				part += fmt.Sprintf(" and number = %s", newverse)
-			}
+		if len(rest) == 0 {
+			break
 		}
-
-		vrange += fmt.Sprintf(" and id <= (select id from verses where %s order by id desc limit 1)", part)
-		return vrange, tokens, nil
+		if rest[0]._type != token_comma && rest[0]._type != token_semicolon {
+			return nil, errors.New("Unexpected token after reference")
+		}
+		separator = rest[0]._type
+		tokens = rest[1:]
 	}
 
-	return part, tokens, nil
+	return ranges, nil
 }
 
-func parsequery(query string) (string, error) {
+// ParseBookRange parses a single book or a range of books such as "Romans-Jude".
+func ParseBookRange(query string) (BookRange, error) {
 	query = strings.TrimSpace(query)
 	query = strings.ToLower(query)
 
 	tokens, err := tokenize(query)
 	if err != nil {
-		return "", err
+		return BookRange{}, err
 	}
 
-	part, tokens, err := parsepart(tokens)
+	first, tokens, err := parsebook(tokens)
 	if err != nil {
-		return "", err
+		return BookRange{}, err
+	}
+	if len(tokens) == 0 {
+		return BookRange{First: first, Last: first}, nil
+	}
+
+	if tokens[0]._type != token_dash {
+		return BookRange{}, errors.New("Unexpected token after book")
+	}
+	last, tokens, err := parsebook(tokens[1:])
+	if err != nil {
+		return BookRange{}, err
+	}
+	if len(tokens) > 0 {
+		return BookRange{}, errors.New("Unexpected token after book")
 	}
 
-	return part, nil
+	return BookRange{First: first, Last: last}, nil
 }
@@ -42,23 +42,87 @@ func TestTokenize(t *testing.T) {
 	}
 }
 
-func TestParseQuery(t *testing.T) {
-	output, err := parsequery("1john1:1")
-	if err != nil {
-		t.Fatalf("Unexpected error: %v", err)
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []Range
+	}{
+		{"1john1:1", []Range{
+			{Reference{"1 John", 1, 1}, Reference{"1 John", 1, 1}},
+		}},
+		{"1john1-2", []Range{
+			{Reference{"1 John", 1, 0}, Reference{"1 John", 2, 0}},
+		}},
+		{"John 3:16-4:2", []Range{
+			{Reference{"John", 3, 16}, Reference{"John", 4, 2}},
+		}},
+		{"Gen 50 - Ex 2", []Range{
+			{Reference{"Genesis", 50, 0}, Reference{"Exodus", 2, 0}},
+		}},
+		{"John 3:16,18; Rom 5:8-10; Ps 23", []Range{
+			{Reference{"John", 3, 16}, Reference{"John", 3, 16}},
+			{Reference{"John", 3, 18}, Reference{"John", 3, 18}},
+			{Reference{"Romans", 5, 8}, Reference{"Romans", 5, 10}},
+			{Reference{"Psalm", 23, 0}, Reference{"Psalm", 23, 0}},
+		}},
+		{"Ps 23, 24", []Range{
+			{Reference{"Psalm", 23, 0}, Reference{"Psalm", 23, 0}},
+			{Reference{"Psalm", 24, 0}, Reference{"Psalm", 24, 0}},
+		}},
+		{"John 3:16, 4:2; 5", []Range{
+			{Reference{"John", 3, 16}, Reference{"John", 3, 16}},
+			{Reference{"John", 4, 2}, Reference{"John", 4, 2}},
+			{Reference{"John", 5, 0}, Reference{"John", 5, 0}},
+		}},
+		{"Song of Solomon 3", []Range{
+			{Reference{"Song of Solomon", 3, 0}, Reference{"Song of Solomon", 3, 0}},
+		}},
+		{"1 Kings 2 - Song of Songs 1", []Range{
+			{Reference{"1 Kings", 2, 0}, Reference{"Song of Solomon", 1, 0}},
+		}},
+		{"John 3:16-18, 20", []Range{
+			{Reference{"John", 3, 16}, Reference{"John", 3, 18}},
+			{Reference{"John", 3, 20}, Reference{"John", 3, 20}},
+		}},
+	}
+
+	for _, test := range tests {
+		output, err := ParseReference(test.query)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", test.query, err)
+		}
+		if len(output) != len(test.expected) {
+			t.Fatalf("Expected %d ranges for %q, got %d: %v", len(test.expected), test.query, len(output), output)
+		}
+		for i := range output {
+			if output[i] != test.expected[i] {
+				t.Fatalf("Unexpected range %d for %q:\nExpected: %v\nActual: %v", i, test.query, test.expected[i], output[i])
+			}
+		}
 	}
 
-	if output != "book = '1 John' and chapter = 1 and number = 1" {
-		t.Fatalf("Unexpected output: %s", output)
+	for _, query := range []string{"", "john", "john 3:", "john 0", "john 3 16", "john 3:16; ;"} {
+		if _, err := ParseReference(query); err == nil {
+			t.Fatalf("Expected error for %q", query)
+		}
 	}
+}
 
-	output, err = parsequery("1john1-2")
-	if err != nil {
-		t.Fatalf("Unexpected error: %v", err)
+func TestRangeQuery(t *testing.T) {
+	where, args := rangequery(Range{Reference{"1 John", 1, 0}, Reference{"1 John", 2, 3}})
+
+	expected := "id >= (SELECT id FROM verses WHERE book = ? AND chapter = ? ORDER BY id ASC LIMIT 1) AND id <= (SELECT id FROM verses WHERE book = ? AND chapter = ? AND number = ? ORDER BY id DESC LIMIT 1)"
+	if where != expected {
+		t.Fatalf("Unexpected condition:\nExpected: %s\nActual: %s", expected, where)
 	}
 
-	expected := "id >= (select id from verses where book = '1 John' and chapter = 1 order by id limit 1) and id <= (select id from verses where book = '1 John' and chapter = 2 order by id desc limit 1)"
-	if output != expected {
-		t.Fatalf("Unexpected output:\nExpected: %s\nActual: %s", expected, output)
+	expectedargs := []any{"1 John", 1, "1 John", 2, 3}
+	if len(args) != len(expectedargs) {
+		t.Fatalf("Expected %d args, got %d", len(expectedargs), len(args))
+	}
+	for i := range args {
+		if args[i] != expectedargs[i] {
+			t.Fatalf("Expected arg %v, got %v", expectedargs[i], args[i])
+		}
 	}
 }
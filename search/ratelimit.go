@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared between concurrent requests. A token is
+// added every interval up to burst tokens, and each request takes one,
+// waiting for it if the bucket is empty.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+
+	// Rather than counting tokens, the time the next token is available is
+	// tracked, which is never allowed to fall more than burst tokens behind.
+	next   time.Time
+	paused time.Time
+}
+
+func NewLimiter(interval time.Duration, burst int) *Limiter {
+	return &Limiter{
+		interval: interval,
+		burst:    max(1, burst),
+	}
+}
+
+// Wait blocks until a token is available and takes it, or until ctx is
+// cancelled.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if earliest := now.Add(-time.Duration(l.burst-1) * l.interval); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	if l.next.Before(l.paused) {
+		l.next = l.paused
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause stops any tokens being handed out for d, such as when the server
+// asks for requests to slow down.
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.paused) {
+		l.paused = until
+	}
+}
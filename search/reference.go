@@ -0,0 +1,48 @@
+package search
+
+import "fmt"
+
+// Reference points at a whole chapter of a book, or at a single verse of it
+// when Verse is non-zero.
+type Reference struct {
+	Book    string
+	Chapter int
+	Verse   int
+}
+
+func (r Reference) String() string {
+	if r.Verse == 0 {
+		return fmt.Sprintf("%s %d", r.Book, r.Chapter)
+	}
+	return fmt.Sprintf("%s %d:%d", r.Book, r.Chapter, r.Verse)
+}
+
+// Range is an inclusive span of verses. When End has no verse the range runs
+// to the end of its chapter, and a single passage has the same Start and End.
+type Range struct {
+	Start Reference
+	End   Reference
+}
+
+func (r Range) String() string {
+	if r.Start == r.End {
+		return r.Start.String()
+	}
+	if r.Start.Book != r.End.Book {
+		return r.Start.String() + "-" + r.End.String()
+	}
+	if r.Start.Chapter == r.End.Chapter && r.Start.Verse != 0 && r.End.Verse != 0 {
+		return fmt.Sprintf("%s-%d", r.Start, r.End.Verse)
+	}
+	if r.End.Verse == 0 {
+		return fmt.Sprintf("%s-%d", r.Start, r.End.Chapter)
+	}
+	return fmt.Sprintf("%s-%d:%d", r.Start, r.End.Chapter, r.End.Verse)
+}
+
+// BookRange restricts a search to the books from First to Last inclusive. The
+// zero value covers every book.
+type BookRange struct {
+	First string
+	Last  string
+}
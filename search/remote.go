@@ -1,28 +1,179 @@
 package search
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/nilptrderef/bgate/reader/model"
+	"golang.org/x/net/html"
 )
 
+// StatusError is returned when BibleGateway responds with anything other than
+// 200 OK. RetryAfter is set when the response asked for requests to wait.
+type StatusError struct {
+	Message    string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func newStatusError(message string, response *http.Response) *StatusError {
+	err := &StatusError{Message: message, StatusCode: response.StatusCode}
+
+	if after := response.Header.Get("Retry-After"); after != "" {
+		if seconds, perr := strconv.Atoi(after); perr == nil {
+			err.RetryAfter = time.Duration(seconds) * time.Second
+		} else if at, perr := http.ParseTime(after); perr == nil {
+			err.RetryAfter = max(0, time.Until(at))
+		}
+	}
+
+	return err
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, http.StatusText(e.StatusCode))
+}
+
+// Throttled reports whether the server asked for requests to slow down.
+func (e *StatusError) Throttled() bool {
+	return e.RetryAfter > 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// Temporary reports whether the request is worth trying again later.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable || e.StatusCode >= 500
+}
+
+// ErrUnexpectedMarkup is matched by every MarkupError, for callers that only
+// need to know that BibleGateway's pages have changed.
+var ErrUnexpectedMarkup = errors.New("unexpected markup from BibleGateway")
+
+// MarkupError is returned when a page from BibleGateway isn't laid out the way
+// it's expected to be. Element is the HTML of the element that couldn't be
+// understood, and Dump is where the whole page was saved if debugging is on.
+type MarkupError struct {
+	Reason  string
+	Element string
+	Dump    string
+	Err     error
+}
+
+func newMarkupError(reason string, element *goquery.Selection, err error) *MarkupError {
+	var html string
+	if element.Length() > 0 {
+		html, _ = goquery.OuterHtml(element.First())
+	}
+	return &MarkupError{Reason: reason, Element: html, Err: err}
+}
+
+func (e *MarkupError) Error() string {
+	message := fmt.Sprintf("%v: %s", ErrUnexpectedMarkup, e.Reason)
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	if e.Dump != "" {
+		message += fmt.Sprintf(" (page saved to %s)", e.Dump)
+	}
+	return message
+}
+
+func (e *MarkupError) Is(target error) bool {
+	return target == ErrUnexpectedMarkup
+}
+
+func (e *MarkupError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultTimeout is how long a request to BibleGateway may take before it's
+// given up on, unless configured otherwise.
+const DefaultTimeout = 30 * time.Second
+
 type Remote struct {
 	// URL format: https://www.biblegateway.com/passage/?search=Genesis+1&version=LSB
 	translation string
+	client      *http.Client
+	debug       bool
+
+	// The translation is only checked once a query fails, and then just once
+	validate sync.Once
+	invalid  error
 }
 
 func NewRemote(translation string) *Remote {
-	return &Remote{translation}
+	return &Remote{translation: translation, client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// SetTimeout sets how long each request to BibleGateway may take, including
+// reading the page. Zero means no timeout.
+func (r *Remote) SetTimeout(timeout time.Duration) {
+	r.client.Timeout = timeout
 }
 
-func (r *Remote) Query(query string) ([]model.Verse, error) {
+// SetDebug sets whether pages that can't be understood are saved to the debug
+// directory in the bgate directory, so they can be attached to bug reports.
+func (r *Remote) SetDebug(debug bool) {
+	r.debug = debug
+}
+
+// dump saves a page that couldn't be understood, recording where in err.
+func (r *Remote) dump(page []byte, name string, err *MarkupError) {
+	if !r.debug {
+		return
+	}
+
+	bgatepath, derr := datadir()
+	if derr != nil {
+		return
+	}
+	debugpath := path.Join(bgatepath, "debug")
+	if os.MkdirAll(debugpath, 0755) != nil {
+		return
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r == ' ' || r == ':' || r == '/' || r == ';' || r == ',' {
+			return '_'
+		}
+		return r
+	}, name)
+	dumppath := path.Join(debugpath, fmt.Sprintf("%s-%s-%s.html", r.translation, name, time.Now().Format("20060102-150405")))
+	if os.WriteFile(dumppath, page, 0644) == nil {
+		err.Dump = dumppath
+	}
+}
+
+// Query looks up a passage on BibleGateway. The first time a query fails the
+// translation is checked against the versions BibleGateway offers, so that a
+// mistyped one is reported as such rather than as a passage that can't be found.
+func (r *Remote) Query(ctx context.Context, query string) ([]model.Verse, error) {
+	verses, err := r.query(ctx, query)
+	if err != nil && ctx.Err() == nil {
+		r.validate.Do(func() {
+			r.invalid = r.Validate(ctx)
+		})
+		if r.invalid != nil {
+			return nil, r.invalid
+		}
+	}
+	return verses, err
+}
+
+func (r *Remote) query(ctx context.Context, query string) ([]model.Verse, error) {
 	base, err := url.Parse("https://www.biblegateway.com/passage/")
 	if err != nil {
 		return nil, err
@@ -33,30 +184,44 @@ func (r *Remote) Query(query string) ([]model.Verse, error) {
 	values.Set("version", r.translation)
 	base.RawQuery = values.Encode()
 
-	request, err := http.NewRequest("GET", base.String(), nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", base.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := http.DefaultClient.Do(request)
+	response, err := r.client.Do(request)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("unable to retrieve passage")
+		return nil, newStatusError("unable to retrieve passage", response)
 	}
 
-	document, err := goquery.NewDocumentFromReader(response.Body)
+	page, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	verses, err := parsePassage(page)
+	var merr *MarkupError
+	if errors.As(err, &merr) {
+		r.dump(page, query, merr)
+	}
+	return verses, err
+}
+
+// parsePassage reads the verses out of a passage page.
+func parsePassage(page []byte) ([]model.Verse, error) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
 	if err != nil {
 		return nil, err
 	}
-	document.Find(".crossreference").Remove()
-	document.Find(".footnote").Remove()
+	footnotes, crossrefs := parseNoteText(document)
 
 	verses := []model.Verse{}
-	document.Find(".passage-table").Each(func(pi int, passage *goquery.Selection) {
+	document.Find(".passage-table").EachWithBreak(func(pi int, passage *goquery.Selection) bool {
 		passage.Find(".translation").Remove()
 
 		var book string
@@ -68,74 +233,212 @@ func (r *Remote) Query(query string) ([]model.Verse, error) {
 
 		var title *string
 		var part int
-		passage.Find(".text").Each(func(li int, line *goquery.Selection) {
+		passage.Find(".text").EachWithBreak(func(li int, line *goquery.Selection) bool {
 			// Store title for the next verse
 			if strings.HasPrefix(line.Parent().Nodes[0].Data, "h") {
+				line.Find(".footnote, .crossreference").Remove()
 				t := line.Text()
 				title = &t
-				return
+				return true
 			}
 
 			class, ok := line.Attr("class")
 			if !ok {
-				fmt.Println("No class on text line")
-				os.Exit(1)
+				err = newMarkupError("no class on text line", line, nil)
+				return false
 			}
 
 			csplit := strings.Split(class, " ")
 			if len(csplit) != 2 {
-				fmt.Println("Unexpected class format")
-				os.Exit(1)
+				err = newMarkupError("unexpected class format", line, nil)
+				return false
 			}
 
 			csplit = strings.Split(csplit[1], "-")
 			if len(csplit) < 3 {
-				fmt.Println("Unexpected inner class format")
-				fmt.Println(csplit)
-				os.Exit(1)
+				err = newMarkupError("unexpected inner class format", line, nil)
+				return false
 			}
 
-			cnum, err := strconv.Atoi(csplit[1])
-			if err != nil {
-				fmt.Println("Failed to parse chapter number:", err)
-				os.Exit(1)
+			cnum, cerr := strconv.Atoi(csplit[1])
+			if cerr != nil {
+				err = newMarkupError("failed to parse chapter number", line, cerr)
+				return false
 			}
-			vnum, err := strconv.Atoi(csplit[2])
-			if err != nil {
-				fmt.Println("Failed to parse verse number:", err)
-				os.Exit(1)
+			vnum, verr := strconv.Atoi(csplit[2])
+			if verr != nil {
+				err = newMarkupError("failed to parse verse number", line, verr)
+				return false
 			}
 
+			paragraph, poetry, indent := lineLayout(line)
 			if line.Find(".versenum").Remove().Length() > 0 || line.Find(".chapternum").Remove().Length() > 0 {
+				text, notes, spans := lineText(line, footnotes, crossrefs)
 				verses = append(verses, model.Verse{
-					Book:    book,
-					Chapter: cnum,
-					Number:  vnum,
-					Part:    1,
-					Text:    line.Text(),
-					Title:   title,
+					Book:      book,
+					Chapter:   cnum,
+					Number:    vnum,
+					Part:      1,
+					Text:      text,
+					Title:     title,
+					Notes:     notes,
+					Spans:     spans,
+					Paragraph: paragraph,
+					Poetry:    poetry,
+					Indent:    indent,
 				})
 				part = 1
 			} else {
+				text, notes, spans := lineText(line, footnotes, crossrefs)
 				verses = append(verses, model.Verse{
-					Book:    book,
-					Chapter: cnum,
-					Number:  vnum,
-					Part:    part + 1,
-					Text:    line.Text(),
-					Title:   title,
+					Book:      book,
+					Chapter:   cnum,
+					Number:    vnum,
+					Part:      part + 1,
+					Text:      text,
+					Title:     title,
+					Notes:     notes,
+					Spans:     spans,
+					Paragraph: paragraph,
+					Poetry:    poetry,
+					Indent:    indent,
 				})
 				part++
 			}
 
 			title = nil
+			return true
 		})
+		return err == nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return verses, nil
 }
 
-func (r *Remote) Booklist() ([]model.Book, error) {
+// indentClass matches the class BibleGateway gives indented lines of poetry,
+// such as "indent-1", but not the "indent-1-breaks" spacing inside them.
+var indentClass = regexp.MustCompile(`(?:^|\s)indent-(\d+)(?:\s|$)`)
+
+// lineLayout works out where a line sits in the structure of the passage:
+// whether it's the first in its paragraph, whether it's poetry and how far
+// it's indented.
+func lineLayout(line *goquery.Selection) (paragraph bool, poetry bool, indent int) {
+	if p := line.Closest("p"); p.Length() > 0 {
+		paragraph = p.Find(".text").First().IsSelection(line)
+	}
+
+	poetry = line.Closest(".poetry").Length() > 0
+
+	line.ParentsUntil(".passage-text").EachWithBreak(func(i int, parent *goquery.Selection) bool {
+		class, _ := parent.Attr("class")
+		if match := indentClass.FindStringSubmatch(class); match != nil {
+			indent, _ = strconv.Atoi(match[1])
+			return false
+		}
+		return true
+	})
+
+	return paragraph, poetry, indent
+}
+
+// parseNoteText reads the text of every footnote and the passages of every
+// cross-reference listed under a passage, by the id their markers link to.
+func parseNoteText(document *goquery.Document) (map[string]string, map[string]string) {
+	footnotes := map[string]string{}
+	document.Find(".footnotes li").Each(func(i int, item *goquery.Selection) {
+		id, _ := item.Attr("id")
+		footnotes[id] = strings.TrimSpace(item.Find(".footnote-text").Text())
+	})
+
+	crossrefs := map[string]string{}
+	document.Find(".crossrefs li").Each(func(i int, item *goquery.Selection) {
+		id, _ := item.Attr("id")
+		var refs []string
+		item.Find(".crossref-link").Each(func(i int, link *goquery.Selection) {
+			refs = append(refs, strings.TrimSpace(link.Text()))
+		})
+		crossrefs[id] = strings.Join(refs, "; ")
+	})
+
+	return footnotes, crossrefs
+}
+
+// lineText returns the text of a line along with the notes marked in it, each
+// placed where its marker was, and the spans of it that are styled.
+func lineText(line *goquery.Selection, footnotes map[string]string, crossrefs map[string]string) (string, []model.Note, []model.Span) {
+	var writer strings.Builder
+	var notes []model.Note
+	var spans []model.Span
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			writer.WriteString(node.Data)
+			return
+		}
+
+		element := line.FindNodes(node)
+		if element.HasClass("footnote") || element.HasClass("crossreference") {
+			note := model.Note{Marker: strings.Trim(element.Text(), "[]() "), Offset: writer.Len()}
+			if element.HasClass("crossreference") {
+				id, _ := element.Attr("data-cr")
+				note.Kind = model.CrossReference
+				note.References = crossrefs[strings.TrimPrefix(id, "#")]
+				note.Text = note.References
+			} else {
+				id, _ := element.Attr("data-fn")
+				note.Kind = model.Footnote
+				note.Text = footnotes[strings.TrimPrefix(id, "#")]
+			}
+			notes = append(notes, note)
+			return
+		}
+
+		kind := spanKind(element)
+		index := len(spans)
+		if kind != "" {
+			spans = append(spans, model.Span{Kind: kind, Offset: writer.Len()})
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+
+		if kind != "" {
+			spans[index].Length = writer.Len() - spans[index].Offset
+			if spans[index].Length == 0 {
+				spans = slices.Delete(spans, index, index+1)
+			}
+		}
+	}
+
+	for _, node := range line.Nodes {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	return writer.String(), notes, spans
+}
+
+// spanKind returns the kind of styling BibleGateway marks an element with, if
+// any.
+func spanKind(element *goquery.Selection) string {
+	switch {
+	case element.HasClass("woj"):
+		return model.WordsOfJesus
+	case element.HasClass("small-caps"):
+		return model.SmallCaps
+	case element.Is("i, em"):
+		return model.Italic
+	}
+	return ""
+}
+
+func (r *Remote) Booklist(ctx context.Context) ([]model.Book, error) {
 	var base = "https://www.biblegateway.com"
 	var booklist string
 	var books []model.Book
@@ -151,29 +454,36 @@ func (r *Remote) Booklist() ([]model.Book, error) {
 		values.Set("version", r.translation)
 		base.RawQuery = values.Encode()
 
-		request, err := http.NewRequest("GET", base.String(), nil)
+		request, err := http.NewRequestWithContext(ctx, "GET", base.String(), nil)
 		if err != nil {
 			return nil, err
 		}
 
-		response, err := http.DefaultClient.Do(request)
+		response, err := r.client.Do(request)
 		if err != nil {
 			return nil, err
 		}
 		defer response.Body.Close()
 
 		if response.StatusCode != http.StatusOK {
-			return nil, errors.New("unable to retrieve passage")
+			return nil, newStatusError("unable to retrieve passage", response)
 		}
 
-		document, err := goquery.NewDocumentFromReader(response.Body)
+		page, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
 		if err != nil {
 			return nil, err
 		}
 
 		link := document.Find(".publisher-info-bottom").Find("a")
 		if link.Length() == 0 {
-			return nil, errors.New("unable to retrieve book list")
+			merr := newMarkupError("no link to the book list", document.Find(".publisher-info-bottom"), nil)
+			r.dump(page, "booklist-link", merr)
+			return nil, merr
 		}
 
 		booklist, _ = link.First().Attr("href")
@@ -181,45 +491,53 @@ func (r *Remote) Booklist() ([]model.Book, error) {
 	}
 
 	{
-		request, err := http.NewRequest("GET", base+booklist, nil)
+		request, err := http.NewRequestWithContext(ctx, "GET", base+booklist, nil)
 		if err != nil {
 			return nil, err
 		}
 
-		response, err := http.DefaultClient.Do(request)
+		response, err := r.client.Do(request)
 		if err != nil {
 			return nil, err
 		}
 		defer response.Body.Close()
 
 		if response.StatusCode != http.StatusOK {
-			return nil, errors.New("unable to retrieve booklist")
+			return nil, newStatusError("unable to retrieve booklist", response)
 		}
 
-		document, err := goquery.NewDocumentFromReader(response.Body)
+		page, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
 		if err != nil {
 			return nil, err
 		}
 
 		rows := document.Find(".infotable").Find("tr").Find(".book-name")
 		rows.Find("svg").Remove()
-		rows.Each(func(i int, row *goquery.Selection) {
+		var merr *MarkupError
+		rows.EachWithBreak(func(i int, row *goquery.Selection) bool {
 			ctext := row.Find(".num-chapters").Text()
-			row.Find(".num-chapters").Remove()
 			chapters, ierr := strconv.Atoi(ctext)
 			if ierr != nil {
-				err = ierr
-				return
+				merr = newMarkupError("failed to parse chapter count", row, ierr)
+				return false
 			}
+			row.Find(".num-chapters").Remove()
 
 			name := strings.TrimSpace(row.Text())
 			books = append(books, model.Book{
 				Name:     name,
 				Chapters: chapters,
 			})
+			return true
 		})
-		if err != nil {
-			return nil, err
+		if merr != nil {
+			r.dump(page, "booklist", merr)
+			return nil, merr
 		}
 	}
 
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+func TestParsePassage(t *testing.T) {
+	page := `<div class="passage-table">
+		<span class="dropdown-display-text">John 11</span>
+		<h3><span class="text John-11-35">Jesus Wept</span></h3>
+		<p><span class="text John-11-35"><sup class="versenum">35 </sup>Jesus wept.</span></p>
+	</div>`
+
+	verses, err := parsePassage([]byte(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(verses) != 1 {
+		t.Fatalf("Expected 1 verse, got %d", len(verses))
+	}
+
+	verse := verses[0]
+	if verse.Book != "John" || verse.Chapter != 11 || verse.Number != 35 || verse.Text != "Jesus wept." || !verse.HasTitle() {
+		t.Fatalf("Unexpected verse: %+v", verse)
+	}
+}
+
+func TestParsePassageNotes(t *testing.T) {
+	page := `<div class="passage-table">
+		<span class="dropdown-display-text">John 3</span>
+		<p><span class="text John-3-3"><sup class="versenum">3 </sup>Jesus answered him,<sup class="crossreference" data-cr="#cen-ESV-26113A">(<a href="#cen-ESV-26113A">A</a>)</sup> unless one is born again<sup class="footnote" data-fn="#fen-ESV-26113a">[<a href="#fen-ESV-26113a">a</a>]</sup> he cannot see.</span></p>
+		<div class="footnotes"><ol><li id="fen-ESV-26113a"><a href="#en-ESV-26113">John 3:3</a> <span class="footnote-text">Or <i>from above</i></span></li></ol></div>
+		<div class="crossrefs"><ol><li id="cen-ESV-26113A"><a href="#en-ESV-26113">John 3:3</a> : <a class="crossref-link" href="#">John 1:13</a>; <a class="crossref-link" href="#">1 Pet 1:23</a></li></ol></div>
+	</div>`
+
+	verses, err := parsePassage([]byte(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(verses) != 1 {
+		t.Fatalf("Expected 1 verse, got %d", len(verses))
+	}
+
+	verse := verses[0]
+	if verse.Text != "Jesus answered him, unless one is born again he cannot see." {
+		t.Fatalf("Unexpected text: %q", verse.Text)
+	}
+
+	expected := []model.Note{
+		{Kind: model.CrossReference, Marker: "A", Offset: 19, Text: "John 1:13; 1 Pet 1:23", References: "John 1:13; 1 Pet 1:23"},
+		{Kind: model.Footnote, Marker: "a", Offset: 44, Text: "Or from above"},
+	}
+	if !reflect.DeepEqual(verse.Notes, expected) {
+		t.Fatalf("Unexpected notes:\nExpected: %+v\nActual: %+v", expected, verse.Notes)
+	}
+}
+
+func TestParsePassageSpans(t *testing.T) {
+	page := `<div class="passage-table">
+		<span class="dropdown-display-text">John 11</span>
+		<p><span class="text John-11-25"><sup class="versenum">25 </sup>Jesus said, <span class="woj">“I am the resurrection<sup class="footnote" data-fn="#fen-a">[<a>a</a>]</sup> of the <span class="small-caps">Lord</span>, <i>and</i> life.”</span></span></p>
+	</div>`
+
+	verses, err := parsePassage([]byte(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(verses) != 1 {
+		t.Fatalf("Expected 1 verse, got %d", len(verses))
+	}
+
+	verse := verses[0]
+	if verse.Text != "Jesus said, “I am the resurrection of the Lord, and life.”" {
+		t.Fatalf("Unexpected text: %q", verse.Text)
+	}
+	if len(verse.Notes) != 1 || verse.Text[:verse.Notes[0].Offset] != "Jesus said, “I am the resurrection" {
+		t.Fatalf("Unexpected notes: %+v", verse.Notes)
+	}
+
+	spanned := map[string]string{}
+	for _, span := range verse.Spans {
+		spanned[span.Kind] = verse.Text[span.Offset : span.Offset+span.Length]
+	}
+	expected := map[string]string{
+		model.WordsOfJesus: "“I am the resurrection of the Lord, and life.”",
+		model.SmallCaps:    "Lord",
+		model.Italic:       "and",
+	}
+	if !reflect.DeepEqual(spanned, expected) {
+		t.Fatalf("Unexpected spans:\nExpected: %v\nActual: %v", expected, spanned)
+	}
+}
+
+func TestParsePassageLayout(t *testing.T) {
+	page := `<div class="passage-table"><div class="passage-text">
+		<span class="dropdown-display-text">Psalm 23</span>
+		<div class="poetry"><p class="line"><span class="text Ps-23-1"><span class="chapternum">23 </span>The Lord is my shepherd;</span><br/><span class="indent-1"><span class="indent-1-breaks">&nbsp;&nbsp;</span><span class="text Ps-23-1">I shall not want.</span></span></p></div>
+		<p><span class="text Ps-23-2"><sup class="versenum">2 </sup>He makes me lie down.</span> <span class="text Ps-23-3"><sup class="versenum">3 </sup>He restores my soul.</span></p>
+	</div></div>`
+
+	verses, err := parsePassage([]byte(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		text      string
+		paragraph bool
+		poetry    bool
+		indent    int
+	}{
+		{"The Lord is my shepherd;", true, true, 0},
+		{"I shall not want.", false, true, 1},
+		{"He makes me lie down.", true, false, 0},
+		{"He restores my soul.", false, false, 0},
+	}
+	if len(verses) != len(expected) {
+		t.Fatalf("Expected %d verses, got %d", len(expected), len(verses))
+	}
+	for i, e := range expected {
+		v := verses[i]
+		if v.Text != e.text || v.Paragraph != e.paragraph || v.Poetry != e.poetry || v.Indent != e.indent {
+			t.Fatalf("Unexpected verse %d: %+v", i, v)
+		}
+	}
+}
+
+func TestParsePassageMarkupError(t *testing.T) {
+	tests := map[string]string{
+		"unexpected class format":       `<p><span class="text Gen-1-1 extra">In the beginning</span></p>`,
+		"unexpected inner class format": `<p><span class="text Gen-1">In the beginning</span></p>`,
+		"failed to parse verse number":  `<p><span class="text Gen-1-one">In the beginning</span></p>`,
+	}
+
+	for reason, line := range tests {
+		page := `<div class="passage-table"><span class="dropdown-display-text">Genesis 1</span>` + line + `</div>`
+
+		_, err := parsePassage([]byte(page))
+		if !errors.Is(err, ErrUnexpectedMarkup) {
+			t.Fatalf("%s: expected ErrUnexpectedMarkup, got %v", reason, err)
+		}
+
+		var merr *MarkupError
+		if !errors.As(err, &merr) || merr.Reason != reason {
+			t.Fatalf("%s: unexpected error %v", reason, err)
+		}
+		if !strings.Contains(merr.Element, "In the beginning") {
+			t.Fatalf("%s: expected the offending element, got %q", reason, merr.Element)
+		}
+	}
+}
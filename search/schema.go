@@ -0,0 +1,132 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrations upgrade a local translation database one schema version at a
+// time, migrations[i] taking it from version i to i+1. Version 0 is a database
+// from before the schema was versioned, holding nothing but verses. New
+// databases are created by running every migration, so changes to the schema
+// must be made by adding a migration rather than editing an old one.
+var migrations = []string{`
+	CREATE TABLE IF NOT EXISTS verses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		book TEXT,
+		chapter INTEGER,
+		number INTEGER,
+		part INTEGER,
+		text TEXT,
+		title TEXT
+	);
+	CREATE TABLE meta (
+		translation TEXT,
+		title TEXT,
+		source TEXT,
+		downloaded DATETIME,
+		version INTEGER
+	);
+	INSERT INTO meta (translation, title, source, version) VALUES ('', '', '', 0);
+	CREATE TABLE books (
+		position INTEGER PRIMARY KEY,
+		name TEXT UNIQUE,
+		chapters INTEGER
+	);
+	INSERT INTO books (name, chapters) SELECT book, max(chapter) FROM verses GROUP BY book ORDER BY min(id);
+	`, `
+	CREATE TABLE notes (
+		verse INTEGER REFERENCES verses (id),
+		kind TEXT,
+		marker TEXT,
+		position INTEGER,
+		text TEXT,
+		refs TEXT
+	);
+	CREATE INDEX notes_verse ON notes (verse);
+	`, `
+	ALTER TABLE verses ADD COLUMN paragraph INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE verses ADD COLUMN poetry INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE verses ADD COLUMN indent INTEGER NOT NULL DEFAULT 0;
+	`, `
+	CREATE TABLE spans (
+		verse INTEGER REFERENCES verses (id),
+		kind TEXT,
+		position INTEGER,
+		length INTEGER
+	);
+	CREATE INDEX spans_verse ON spans (verse);
+	`,
+}
+
+// latestVersion is the version of the schema local databases are migrated to.
+var latestVersion = len(migrations)
+
+// Meta describes where a local translation came from.
+type Meta struct {
+	Translation string       `db:"translation"`
+	Title       string       `db:"title"`
+	Source      string       `db:"source"`
+	Downloaded  sql.NullTime `db:"downloaded"`
+	Version     int          `db:"version"`
+}
+
+// schemaVersion returns the schema version of a database.
+func schemaVersion(db sqlx.Queryer) (int, error) {
+	var tables int
+	err := sqlx.Get(db, &tables, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'meta'")
+	if err != nil || tables == 0 {
+		return 0, err
+	}
+
+	var version int
+	err = sqlx.Get(db, &version, "SELECT version FROM meta")
+	return version, err
+}
+
+// migrate brings a database up to the current schema, creating it if it's
+// empty. Each migration runs in its own transaction, so a failed migration
+// leaves the database at the version before it.
+func migrate(db *sqlx.DB, translation string) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > latestVersion {
+		return fmt.Errorf("Local copy of %s was written by a newer version of bgate (schema %d, supported %d)", translation, version, latestVersion)
+	}
+
+	for ; version < latestVersion; version++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(migrations[version])
+		if err == nil {
+			_, err = tx.Exec("UPDATE meta SET version = ?, translation = coalesce(nullif(translation, ''), ?)", version+1, translation)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migrating local copy of %s to schema %d: %w", translation, version+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Meta returns the metadata of the translation.
+func (l *Local) Meta() (Meta, error) {
+	return readMeta(l.db)
+}
+
+func readMeta(db sqlx.Queryer) (Meta, error) {
+	var meta Meta
+	err := sqlx.Get(db, &meta, "SELECT translation, title, source, downloaded, version FROM meta")
+	return meta, err
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nilptrderef/bgate/reader/model"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", path.Join(t.TempDir(), "OLD.sql"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	// A database as written before the schema was versioned
+	_, err = db.Exec(`
+		CREATE TABLE verses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		book TEXT,
+		chapter INTEGER,
+		number INTEGER,
+		part INTEGER,
+		text TEXT,
+		title TEXT
+	);
+	INSERT INTO verses (book, chapter, number, part, text) VALUES
+		('Genesis', 1, 1, 1, 'In the beginning'),
+		('Genesis', 2, 1, 1, 'Thus the heavens'),
+		('Exodus', 1, 1, 1, 'These are the names');`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Migrating twice should leave it as it was after the first time
+	for range 2 {
+		err = migrate(db, "OLD")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	local := &Local{db, "OLD"}
+	books, err := local.Booklist(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []model.Book{{Name: "Genesis", Chapters: 2}, {Name: "Exodus", Chapters: 1}}
+	if !reflect.DeepEqual(books, expected) {
+		t.Fatalf("Expected %v, got %v", expected, books)
+	}
+
+	meta, err := local.Meta()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meta.Translation != "OLD" || meta.Version != latestVersion || meta.Downloaded.Valid {
+		t.Fatalf("Unexpected metadata after migrating: %+v", meta)
+	}
+}
+
+func TestOpenLocal(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := path.Join(dir, "MISSING.sql")
+	if _, err := openLocal(missing, "MISSING"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected a missing copy to not be found, got %v", err)
+	}
+	if _, err := os.Stat(missing); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected nothing to be created for a missing copy")
+	}
+
+	// A database from before the schema was versioned is migrated
+	sqlpath := path.Join(dir, "OLD.sql")
+	db, err := sqlx.Open("sqlite3", sqlpath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = db.Exec("CREATE TABLE verses (id INTEGER PRIMARY KEY AUTOINCREMENT, book TEXT, chapter INTEGER, number INTEGER, part INTEGER, text TEXT, title TEXT)")
+	db.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	local, err := openLocal(sqlpath, "OLD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	meta, err := local.Meta()
+	local.Close()
+	if err != nil || meta.Version != latestVersion {
+		t.Fatalf("Expected the copy to be migrated, got %+v (%v)", meta, err)
+	}
+
+	// Once up to date it's never written to, so it can be read-only
+	info, err := os.Stat(sqlpath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = os.Chmod(sqlpath, 0444)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	local, err = openLocal(sqlpath, "OLD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer local.Close()
+	if _, err := local.VerseCount(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := local.db.Exec("DELETE FROM verses"); err == nil {
+		t.Fatalf("Expected an up to date copy to be opened read-only")
+	}
+	after, err := os.Stat(sqlpath)
+	if err != nil || !after.ModTime().Equal(info.ModTime()) {
+		t.Fatalf("Expected an up to date copy to be left as it was")
+	}
+}
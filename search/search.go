@@ -1,11 +1,15 @@
 package search
 
 import (
+	"context"
+
 	"github.com/nilptrderef/bgate/reader/model"
 )
 
+// Searcher looks up passages in a translation. Lookups stop early with the
+// context's error once it's cancelled.
 type Searcher interface {
-	Query(query string) ([]model.Verse, error)
-	Booklist() ([]model.Book, error)
+	Query(ctx context.Context, query string) ([]model.Verse, error)
+	Booklist(ctx context.Context) ([]model.Book, error)
 	Translation() string
 }
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LocalTranslation describes a downloaded translation. Err is set when its
+// file couldn't be read, leaving only the name, size and modification time.
+type LocalTranslation struct {
+	Name       string
+	Title      string
+	Size       int64
+	Verses     int
+	Downloaded time.Time
+	Err        error
+}
+
+// LocalTranslations lists every downloaded translation. Each is only read, so
+// that listing them never migrates or locks them, and one that can't be read
+// is listed with its error rather than failing the rest.
+func LocalTranslations() ([]LocalTranslation, error) {
+	bgatepath, err := datadir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(bgatepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var translations []LocalTranslation
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		// Translations downloaded before the date was recorded fall back
+		// to when the file was last written.
+		translation := LocalTranslation{Name: name, Size: info.Size(), Downloaded: info.ModTime()}
+		translation.Err = readLocal(path.Join(bgatepath, entry.Name()), &translation)
+		translations = append(translations, translation)
+	}
+
+	return translations, nil
+}
+
+// readLocal fills in what a local copy records about itself without migrating
+// it, so copies from before there was any metadata only have their verses
+// counted.
+func readLocal(sqlpath string, translation *LocalTranslation) error {
+	db, err := sqlx.Open("sqlite3", readonly(sqlpath))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > 0 {
+		meta, err := readMeta(db)
+		if err != nil {
+			return err
+		}
+		translation.Title = meta.Title
+		if meta.Downloaded.Valid {
+			translation.Downloaded = meta.Downloaded.Time
+		}
+	}
+
+	return db.Get(&translation.Verses, "SELECT count(*) FROM verses WHERE part = 1")
+}
+
+// RemoveLocal deletes the local copy of a translation, along with any
+// interrupted download of it.
+func RemoveLocal(translation string) error {
+	sqlpath, err := LocalPath(translation)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, name := range []string{sqlpath, sqlpath + ".part"} {
+		err = os.Remove(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("No local copy of %s found", translation)
+	}
+	return nil
+}
+
+// ChapterProblem is a chapter that failed verification.
+type ChapterProblem struct {
+	Book    string
+	Chapter int
+	Missing bool
+}
+
+func (p ChapterProblem) String() string {
+	if p.Missing {
+		return fmt.Sprintf("%s %d is missing", p.Book, p.Chapter)
+	}
+	return fmt.Sprintf("%s %d is empty", p.Book, p.Chapter)
+}
+
+// VerseCount returns the number of verses, counting each verse once no matter
+// how many parts it was split into.
+func (l *Local) VerseCount() (int, error) {
+	var count int
+	err := l.db.Get(&count, "SELECT count(*) FROM verses WHERE part = 1")
+	return count, err
+}
+
+// Verify checks every chapter of every book in the booklist, reporting any
+// chapter without verses as missing and any without text as empty.
+func (l *Local) Verify(ctx context.Context) ([]ChapterProblem, error) {
+	books, err := l.Booklist(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapters []struct {
+		Book    string `db:"book"`
+		Chapter int    `db:"chapter"`
+		Text    int    `db:"text"`
+	}
+	err = l.db.SelectContext(ctx, &chapters, "SELECT book, chapter, sum(length(trim(text))) AS text FROM verses GROUP BY book, chapter")
+	if err != nil {
+		return nil, err
+	}
+
+	text := map[chapterkey]int{}
+	for _, c := range chapters {
+		text[chapterkey{c.Book, c.Chapter}] = c.Text
+	}
+
+	var problems []ChapterProblem
+	for _, book := range books {
+		for chapter := 1; chapter <= book.Chapters; chapter++ {
+			length, ok := text[chapterkey{book.Name, chapter}]
+			if !ok {
+				problems = append(problems, ChapterProblem{book.Name, chapter, true})
+			} else if length == 0 {
+				problems = append(problems, ChapterProblem{book.Name, chapter, false})
+			}
+		}
+	}
+
+	return problems, nil
+}
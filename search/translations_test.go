@@ -0,0 +1,95 @@
+package search
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestLocalTranslations(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	bgatepath, err := datadir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = os.MkdirAll(bgatepath, 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A copy from before the schema was versioned, which listing shouldn't
+	// migrate, and one that isn't a database at all
+	db, err := sqlx.Open("sqlite3", path.Join(bgatepath, "OLD.sql"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE verses (id INTEGER PRIMARY KEY AUTOINCREMENT, book TEXT, chapter INTEGER, number INTEGER, part INTEGER, text TEXT, title TEXT);
+		INSERT INTO verses (book, chapter, number, part, text) VALUES
+			('Genesis', 1, 1, 1, 'In the beginning'),
+			('Genesis', 1, 2, 1, 'Now the earth'),
+			('Genesis', 1, 2, 2, 'was formless');`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = os.WriteFile(path.Join(bgatepath, "BAD.sql"), []byte("not a database, just some text that's long enough to be read"), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	locals, err := LocalTranslations()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(locals) != 2 || locals[0].Name != "BAD" || locals[1].Name != "OLD" {
+		t.Fatalf("Expected BAD and OLD to be listed, got %+v", locals)
+	}
+	if locals[0].Err == nil {
+		t.Fatalf("Expected BAD to be listed as unreadable")
+	}
+	if locals[1].Err != nil || locals[1].Verses != 2 {
+		t.Fatalf("Expected OLD to have 2 verses, got %+v", locals[1])
+	}
+
+	db, err = sqlx.Open("sqlite3", path.Join(bgatepath, "OLD.sql"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	version, err := schemaVersion(db)
+	db.Close()
+	if err != nil || version != 0 {
+		t.Fatalf("Expected listing to leave OLD unmigrated, got version %d (%v)", version, err)
+	}
+}
+
+func TestRemoveLocal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	partial, err := LocalPath("PART")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = os.MkdirAll(path.Dir(partial), 0755)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = os.WriteFile(partial+".part", nil, 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// An interrupted download is removed even though it never finished
+	err = RemoveLocal("PART")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(partial + ".part"); !os.IsNotExist(err) {
+		t.Fatalf("Expected the interrupted download to be removed")
+	}
+
+	if RemoveLocal("PART") == nil {
+		t.Fatalf("Expected nothing left to remove")
+	}
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Version is a translation of the Bible offered by BibleGateway.
+type Version struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Language string `json:"language"`
+}
+
+// versionsTTL is how long the list of versions is cached before it's fetched
+// again.
+const versionsTTL = 7 * 24 * time.Hour
+
+type versionCache struct {
+	Fetched  time.Time `json:"fetched"`
+	Versions []Version `json:"versions"`
+}
+
+// versionName matches the link text of a version, such as
+// "English Standard Version (ESV)".
+var versionName = regexp.MustCompile(`^(.+?)\s*\(([^()]+)\)$`)
+
+// Versions returns every version offered by BibleGateway. The list is cached
+// in the bgate directory, and only fetched again once the cache is older than
+// a week or when refresh is set, using remote so that its timeout and debug
+// settings apply. A stale cache is still used if fetching fails, so that the
+// list remains available offline.
+func Versions(ctx context.Context, remote *Remote, refresh bool) ([]Version, error) {
+	cachepath, err := versionsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := readVersionCache(cachepath)
+	if !refresh && len(cache.Versions) > 0 && time.Since(cache.Fetched) < versionsTTL {
+		return cache.Versions, nil
+	}
+
+	versions, err := remote.Versions(ctx)
+	if err != nil {
+		if !refresh && len(cache.Versions) > 0 {
+			return cache.Versions, nil
+		}
+		return nil, err
+	}
+
+	data, err := json.Marshal(versionCache{time.Now(), versions})
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(path.Dir(cachepath), 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(cachepath, data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// CachedVersions returns the cached list of versions without ever reaching
+// out to BibleGateway, reporting false if nothing has been cached.
+func CachedVersions() ([]Version, bool) {
+	cachepath, err := versionsPath()
+	if err != nil {
+		return nil, false
+	}
+
+	cache := readVersionCache(cachepath)
+	return cache.Versions, len(cache.Versions) > 0
+}
+
+func versionsPath() (string, error) {
+	bgatepath, err := datadir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(bgatepath, "versions.json"), nil
+}
+
+// readVersionCache reads the cached list of versions, treating a missing or
+// unreadable cache as empty.
+func readVersionCache(cachepath string) versionCache {
+	var cache versionCache
+	if data, err := os.ReadFile(cachepath); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// Versions scrapes the list of versions from BibleGateway, without caching
+// it.
+func (r *Remote) Versions(ctx context.Context) ([]Version, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", "https://www.biblegateway.com/versions/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, newStatusError("unable to retrieve versions", response)
+	}
+
+	page, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := parseVersions(bytes.NewReader(page))
+	var merr *MarkupError
+	if errors.As(err, &merr) {
+		r.dump(page, "versions", merr)
+	}
+	return versions, err
+}
+
+// parseVersions reads the versions out of the table on BibleGateway's
+// versions page, where each language has a heading row followed by a row per
+// version in it.
+func parseVersions(r io.Reader) ([]Version, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	var language string
+	seen := map[string]bool{}
+	document.Find(".infotable tr").Each(func(i int, row *goquery.Selection) {
+		if row.HasClass("language-row") {
+			language = strings.Trim(strings.TrimSpace(row.Text()), "—- ")
+			return
+		}
+
+		name := strings.TrimSpace(row.Find(".translation-name a").First().Text())
+		match := versionName.FindStringSubmatch(name)
+		if match == nil || seen[match[2]] {
+			return
+		}
+
+		seen[match[2]] = true
+		versions = append(versions, Version{Code: match[2], Name: match[1], Language: language})
+	})
+
+	if len(versions) == 0 {
+		return nil, newMarkupError("no versions found", document.Find(".infotable"), nil)
+	}
+	return versions, nil
+}
+
+// FindVersion looks up a version by its code, regardless of case as
+// BibleGateway does.
+func FindVersion(versions []Version, code string) (Version, bool) {
+	for _, version := range versions {
+		if strings.EqualFold(version.Code, code) {
+			return version, true
+		}
+	}
+	return Version{}, false
+}
+
+// CheckVersion fails if translation isn't among versions.
+func CheckVersion(versions []Version, translation string) error {
+	if _, ok := FindVersion(versions, translation); !ok {
+		return fmt.Errorf("Unknown translation %s, run `bgate translations remote` to list the available ones", translation)
+	}
+	return nil
+}
+
+// Validate checks that BibleGateway offers the translation being searched. If
+// the list of versions can't be retrieved the translation is given the benefit
+// of the doubt, since BibleGateway will reject it anyway if it's wrong.
+func (r *Remote) Validate(ctx context.Context) error {
+	versions, err := Versions(ctx, r, false)
+	if err != nil {
+		return nil
+	}
+	return CheckVersion(versions, r.translation)
+}
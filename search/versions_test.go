@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVersions(t *testing.T) {
+	page := `<table class="infotable">
+		<tr class="language-row"><td>—Amuzgo de Guerrero (AMU)—</td></tr>
+		<tr><td class="translation-name"><a href="/versions/Amuzgo-de-Guerrero-AMU/">Amuzgo de Guerrero (AMU)</a></td></tr>
+		<tr class="language-row"><td>—English (EN)—</td></tr>
+		<tr><td class="translation-name"><a href="/versions/English-Standard-Version-ESV-Bible/">English Standard Version (ESV)</a></td></tr>
+		<tr><td class="translation-name"><a href="/versions/Legacy-Standard-Bible-LSB/">Legacy Standard Bible (LSB)</a></td></tr>
+		<tr><td class="translation-name"><a href="/versions/English-Standard-Version-ESV-Bible/">English Standard Version (ESV)</a></td></tr>
+	</table>`
+
+	versions, err := parseVersions(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Version{
+		{Code: "AMU", Name: "Amuzgo de Guerrero", Language: "Amuzgo de Guerrero (AMU)"},
+		{Code: "ESV", Name: "English Standard Version", Language: "English (EN)"},
+		{Code: "LSB", Name: "Legacy Standard Bible", Language: "English (EN)"},
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("Expected %v, got %v", expected, versions)
+	}
+
+	if version, ok := FindVersion(versions, "lsb"); !ok || version.Code != "LSB" {
+		t.Fatalf("Expected to find LSB regardless of case, got %v", version)
+	}
+}
+
+// fakesite stands in for BibleGateway, listing a single version and failing
+// every passage, while counting the requests for each page.
+type fakesite map[string]int
+
+func (f fakesite) RoundTrip(request *http.Request) (*http.Response, error) {
+	f[request.URL.Path]++
+	response := &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+	if request.URL.Path == "/versions/" {
+		response.StatusCode = http.StatusOK
+		response.Body = io.NopCloser(strings.NewReader(`<table class="infotable">
+			<tr><td class="translation-name"><a href="/versions/Legacy-Standard-Bible-LSB/">Legacy Standard Bible (LSB)</a></td></tr>
+		</table>`))
+	}
+	return response, nil
+}
+
+func TestRemoteValidatesOnFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	site := fakesite{}
+	remote := NewRemote("XYZ")
+	remote.client.Transport = site
+
+	for range 2 {
+		_, err := remote.Query(context.Background(), "John 3")
+		if err == nil || !strings.Contains(err.Error(), "Unknown translation XYZ") {
+			t.Fatalf("Expected the translation to be reported as unknown, got %v", err)
+		}
+	}
+	if site["/versions/"] != 1 || site["/passage/"] != 2 {
+		t.Fatalf("Expected the versions to be fetched once through the remote's client, got %v", site)
+	}
+
+	// Once cached the versions are known without asking again
+	versions, ok := CachedVersions()
+	if !ok || CheckVersion(versions, "lsb") != nil || CheckVersion(versions, "XYZ") == nil {
+		t.Fatalf("Expected the versions to be cached, got %v", versions)
+	}
+
+	remote = NewRemote("LSB")
+	remote.client.Transport = site
+	var serr *StatusError
+	if _, err := remote.Query(context.Background(), "John 3"); !errors.As(err, &serr) {
+		t.Fatalf("Expected the failure of a known translation to be kept, got %v", err)
+	}
+	if site["/versions/"] != 1 {
+		t.Fatalf("Expected the cached versions to be used, got %v", site)
+	}
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nilptrderef/bgate/search"
+)
+
+// Bookmark marks a verse by reference alone, so that it can be opened in any
+// translation.
+type Bookmark struct {
+	ID      int       `db:"id"`
+	Book    string    `db:"book"`
+	Chapter int       `db:"chapter"`
+	Verse   int       `db:"verse"`
+	Label   string    `db:"label"`
+	Created time.Time `db:"created"`
+}
+
+func (b Bookmark) Reference() search.Reference {
+	return search.Reference{Book: b.Book, Chapter: b.Chapter, Verse: b.Verse}
+}
+
+func (b Bookmark) String() string {
+	if b.Label == "" {
+		return b.Reference().String()
+	}
+	return fmt.Sprintf("%s (%s)", b.Reference(), b.Label)
+}
+
+func (s *Store) AddBookmark(reference search.Reference, label string) (Bookmark, error) {
+	result, err := s.db.Exec("INSERT INTO bookmarks (book, chapter, verse, label) VALUES (?, ?, ?, ?)", reference.Book, reference.Chapter, reference.Verse, label)
+	if err != nil {
+		return Bookmark{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Bookmark{}, err
+	}
+
+	var bookmark Bookmark
+	err = s.db.Get(&bookmark, "SELECT id, book, chapter, verse, label, created FROM bookmarks WHERE id = ?", id)
+	return bookmark, err
+}
+
+// Bookmarks returns every bookmark, newest first.
+func (s *Store) Bookmarks() ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	err := s.db.Select(&bookmarks, "SELECT id, book, chapter, verse, label, created FROM bookmarks ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+func (s *Store) RemoveBookmark(id int) error {
+	result, err := s.db.Exec("DELETE FROM bookmarks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("No bookmark with id %d", id)
+	}
+	return nil
+}
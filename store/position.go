@@ -0,0 +1,38 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Position is where the reader was left for a translation, or for a set of
+// translations read side by side.
+type Position struct {
+	Translation string    `db:"translation"`
+	Query       string    `db:"query"`
+	YOffset     int       `db:"yoffset"`
+	Updated     time.Time `db:"updated"`
+}
+
+func (s *Store) SavePosition(translation string, query string, yoffset int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO positions (translation, query, yoffset, updated) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(translation) DO UPDATE SET query = excluded.query, yoffset = excluded.yoffset, updated = excluded.updated`,
+		translation, query, yoffset)
+	return err
+}
+
+// Position returns the last position saved for translation, reporting false
+// if there isn't one.
+func (s *Store) Position(translation string) (Position, bool, error) {
+	var position Position
+	err := s.db.Get(&position, "SELECT translation, query, yoffset, updated FROM positions WHERE translation = ?", translation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Position{}, false, nil
+	}
+	if err != nil {
+		return Position{}, false, err
+	}
+	return position, true, nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"path"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var tables = []string{`
+	CREATE TABLE IF NOT EXISTS bookmarks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		book TEXT,
+		chapter INTEGER,
+		verse INTEGER,
+		label TEXT,
+		created DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`, `
+	CREATE TABLE IF NOT EXISTS positions (
+		translation TEXT PRIMARY KEY,
+		query TEXT,
+		yoffset INTEGER,
+		updated DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+}
+
+// Store holds everything bgate remembers for the user between sessions, kept
+// in a SQLite file alongside the downloaded translations.
+type Store struct {
+	db *sqlx.DB
+}
+
+func Open() (*Store, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	bgatepath := path.Join(home, ".bgate")
+	err = os.MkdirAll(bgatepath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlx.Open("sqlite3", path.Join(bgatepath, "user.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		_, err = db.Exec(table)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &Store{db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}